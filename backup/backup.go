@@ -0,0 +1,165 @@
+/*
+Package backup keeps device configurations as files in a local git repository,
+one file per host and one commit per run.
+*/
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	fileExt  = ".cfg"
+	filePerm = 0644
+	dirPerm  = 0755
+
+	gitUserName  = "console"
+	gitUserEmail = "console@localhost"
+)
+
+// DefaultVolatilePatterns match the lines that change without configuration changes.
+var DefaultVolatilePatterns = []string{
+	`^Building configuration`,
+	`^Current configuration\s*:`,
+	`^! (Last configuration change|NVRAM config last updated)`,
+	`^ntp clock-period`,
+	`uptime is`,
+	`^\d{2}:\d{2}:\d{2}(\.\d+)? \w+ \w{3} \w{3} \d+ \d{4}$`, // show clock
+}
+
+var ErrGit = errors.New("git failed")
+
+type Repo struct {
+	dir      string
+	volatile []*regexp.Regexp
+	mu       sync.Mutex
+}
+
+func (r *Repo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=" + gitUserName,
+		"-c", "user.email=" + gitUserEmail,
+	}, args...)...)
+	cmd.Dir = r.dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: git %s: %w: %s", ErrGit, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return string(out), nil
+}
+
+// Strip removes the volatile lines.
+func (r *Repo) Strip(data string) string {
+	lines := strings.SplitAfter(data, "\n")
+	kept := lines[:0]
+
+	for _, line := range lines {
+		volatile := false
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		for _, re := range r.volatile {
+			if re.MatchString(trimmed) {
+				volatile = true
+				break
+			}
+		}
+
+		if !volatile {
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, "")
+}
+
+// Save writes the configuration of the host without volatile lines. It is safe for concurrent use.
+func (r *Repo) Save(host string, data string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.WriteFile(path.Join(r.dir, host+fileExt), []byte(r.Strip(data)), filePerm); err != nil {
+		return fmt.Errorf("cannot save backup: %w", err)
+	}
+
+	return nil
+}
+
+// Commit commits all saved configurations and returns the hosts changed since the previous commit.
+func (r *Repo) Commit(message string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.git("add", "-A"); err != nil {
+		return nil, err
+	}
+
+	status, err := r.git("status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+
+	for _, line := range strings.Split(status, "\n") {
+		if len(line) < 4 {
+			continue
+		}
+
+		name := strings.Trim(line[3:], `"`)
+		if strings.HasSuffix(name, fileExt) {
+			changed = append(changed, strings.TrimSuffix(name, fileExt))
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(changed)
+
+	if _, err = r.git("commit", "-q", "-m", message); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// Open opens the repository in dir, creating it if needed.
+func Open(dir string, volatilePatterns []string) (*Repo, error) {
+	r := &Repo{dir: dir}
+
+	for _, pattern := range volatilePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile volatile pattern: %w", err)
+		}
+
+		r.volatile = append(r.volatile, re)
+	}
+
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, fmt.Errorf("cannot create backup dir: %w", err)
+	}
+
+	if _, err := os.Stat(path.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if _, err2 := r.git("init", "-q"); err2 != nil {
+			return nil, err2
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testConfig = `Building configuration...
+
+Current configuration : 1234 bytes
+! Last configuration change at 10:00:00 UTC Tue Apr 5 2022
+hostname sw1
+ntp clock-period 36028797
+interface Gi0/1
+ description uplink
+end
+`
+
+type BackupTestSuite struct {
+	suite.Suite
+	dir  string
+	repo *Repo
+}
+
+func (suite *BackupTestSuite) SetupTest() {
+	suite.dir = path.Join(suite.T().TempDir(), "backup")
+
+	var err error
+	suite.repo, err = Open(suite.dir, DefaultVolatilePatterns)
+	suite.Require().NoError(err)
+}
+
+func (suite *BackupTestSuite) TestStrip() {
+	suite.Equal("\nhostname sw1\ninterface Gi0/1\n description uplink\nend\n", suite.repo.Strip(testConfig))
+}
+
+func (suite *BackupTestSuite) TestCommit() {
+	suite.Require().NoError(suite.repo.Save("10.0.0.1", testConfig))
+	suite.Require().NoError(suite.repo.Save("10.0.0.2", testConfig))
+
+	changed, err := suite.repo.Commit("backup 1")
+	suite.Require().NoError(err)
+	suite.Equal([]string{"10.0.0.1", "10.0.0.2"}, changed)
+
+	// Only volatile lines changed on the first host.
+	suite.Require().NoError(suite.repo.Save("10.0.0.1", "ntp clock-period 1\n"+testConfig))
+	suite.Require().NoError(suite.repo.Save("10.0.0.2", testConfig+"vlan 10\n"))
+
+	changed, err = suite.repo.Commit("backup 2")
+	suite.Require().NoError(err)
+	suite.Equal([]string{"10.0.0.2"}, changed)
+
+	changed, err = suite.repo.Commit("backup 3")
+	suite.Require().NoError(err)
+	suite.Empty(changed)
+
+	log, err := suite.repo.git("log", "--format=%s")
+	suite.Require().NoError(err)
+	suite.Equal("backup 2\nbackup 1\n", log)
+
+	// Reopen the existing repository.
+	_, err = Open(suite.dir, nil)
+	suite.NoError(err)
+	_, err = os.Stat(path.Join(suite.dir, "10.0.0.1.cfg"))
+	suite.NoError(err)
+}
+
+func (suite *BackupTestSuite) TestBadPattern() {
+	_, err := Open(suite.dir, []string{"("})
+	suite.Error(err)
+}
+
+func TestBackupTestSuite(t *testing.T) {
+	suite.Run(t, new(BackupTestSuite))
+}
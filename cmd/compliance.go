@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/jgivc/console/compliance"
+)
+
+const exitNonCompliant = 5 // Some compliance rules failed
+
+type complianceResult struct {
+	Host string `json:"host"`
+	compliance.Result
+}
+
+// complianceReport collects the compliance results of all hosts.
+type complianceReport struct {
+	rules   *compliance.Rules
+	mu      sync.Mutex
+	results []complianceResult
+}
+
+func (r *complianceReport) add(host string, results []compliance.Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, res := range results {
+		r.results = append(r.results, complianceResult{Host: host, Result: res})
+	}
+}
+
+func (r *complianceReport) check(host string, outputs map[string]string) {
+	r.add(host, r.rules.Check(outputs))
+}
+
+// skip reports every rule as error for the host that cannot be checked.
+func (r *complianceReport) skip(host string, err error) {
+	results := make([]compliance.Result, 0, len(r.rules.Rules))
+	for i := range r.rules.Rules {
+		results = append(results, compliance.Result{
+			Rule:    r.rules.Rules[i].Name,
+			Status:  compliance.StatusError,
+			Message: err.Error(),
+		})
+	}
+
+	r.add(host, results)
+}
+
+func (r *complianceReport) sorted() []complianceResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := append([]complianceResult(nil), r.results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Host < results[j].Host
+	})
+
+	return results
+}
+
+func (r *complianceReport) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tRULE\tSTATUS\tMESSAGE")
+
+	for _, res := range r.sorted() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", res.Host, res.Rule, res.Status, res.Message)
+	}
+
+	return tw.Flush()
+}
+
+func (r *complianceReport) writeJSON(fileName string) error {
+	b, err := json.MarshalIndent(r.sorted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal compliance report: %w", err)
+	}
+
+	if err2 := os.WriteFile(fileName, b, outputFilePerm); err2 != nil {
+		return fmt.Errorf("cannot write compliance report: %w", err2)
+	}
+
+	return nil
+}
+
+// failed returns true if any rule did not pass on any host.
+func (r *complianceReport) failed() bool {
+	for _, res := range r.sorted() {
+		if res.Status != compliance.StatusPass {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendMissing appends the commands which are not in the list yet.
+func appendMissing(list, commands []string) []string {
+	for _, cmd := range commands {
+		exists := false
+		for _, c := range list {
+			exists = exists || c == cmd
+		}
+
+		if !exists {
+			list = append(list, cmd)
+		}
+	}
+
+	return list
+}
+
+func newComplianceReport(fileName string) (*complianceReport, error) {
+	rules, err := compliance.Load(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &complianceReport{rules: rules}, nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jgivc/console/conftree"
+	"github.com/jgivc/console/diff"
+)
+
+const (
+	exitChanged = 4 // Output differs from the baseline
+
+	baselineFileExt  = ".txt"
+	baselineDirPerm  = 0755
+	baselineFilePerm = 0644
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^\w.\-]+`)
+
+type baselineKey struct {
+	host    string
+	command string // commandFileName of the command
+}
+
+func newBaselineKey(host, command string) baselineKey {
+	return baselineKey{host: host, command: commandFileName(command)}
+}
+
+// commandFileName returns the command as a file name, e.g. "sh ip route" -> "sh_ip_route".
+func commandFileName(command string) string {
+	return strings.Trim(unsafeFileChars.ReplaceAllString(command, "_"), "_")
+}
+
+/*
+baseline is the output of the previous run. It is loaded from:
+
+	dir            dir/host/command.txt files, as saved with -save-baseline
+	results.jsonl  results written with -o jsonl, a JSON array of results is accepted too
+*/
+type baseline map[baselineKey]string
+
+func loadBaseline(source string) (baseline, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open baseline: %w", err)
+	}
+
+	if info.IsDir() {
+		return loadBaselineDir(source)
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open baseline: %w", err)
+	}
+	defer f.Close()
+
+	return loadBaselineJSON(f)
+}
+
+func loadBaselineDir(dir string) (baseline, error) {
+	hosts, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read baseline: %w", err)
+	}
+
+	b := make(baseline)
+
+	for _, h := range hosts {
+		if !h.IsDir() {
+			continue
+		}
+
+		files, err2 := os.ReadDir(path.Join(dir, h.Name()))
+		if err2 != nil {
+			return nil, fmt.Errorf("cannot read baseline: %w", err2)
+		}
+
+		for _, f := range files {
+			if f.IsDir() || path.Ext(f.Name()) != baselineFileExt {
+				continue
+			}
+
+			data, err3 := os.ReadFile(path.Join(dir, h.Name(), f.Name()))
+			if err3 != nil {
+				return nil, fmt.Errorf("cannot read baseline: %w", err3)
+			}
+
+			b[baselineKey{host: h.Name(), command: strings.TrimSuffix(f.Name(), baselineFileExt)}] = string(data)
+		}
+	}
+
+	return b, nil
+}
+
+func loadBaselineJSON(r io.Reader) (baseline, error) {
+	b := make(baseline)
+	dec := json.NewDecoder(r)
+
+	add := func(res *result) {
+		// Failed commands have no output to compare with.
+		if res.Error == "" && res.Command != "" {
+			b[newBaselineKey(res.Host, res.Command)] = res.Output
+		}
+	}
+
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return b, nil
+			}
+
+			return nil, fmt.Errorf("cannot parse baseline: %w", err)
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			var results []result
+			if err := json.Unmarshal(raw, &results); err != nil {
+				return nil, fmt.Errorf("cannot parse baseline: %w", err)
+			}
+
+			for i := range results {
+				add(&results[i])
+			}
+
+			continue
+		}
+
+		var res result
+		if err := json.Unmarshal(raw, &res); err != nil {
+			return nil, fmt.Errorf("cannot parse baseline: %w", err)
+		}
+
+		add(&res)
+	}
+}
+
+// differ compares the command output with the baseline and writes unified diffs.
+type differ struct {
+	base     baseline
+	ignore   []*regexp.Regexp
+	semantic bool // Compare config trees ignoring the order of sibling lines
+	saveDir  string
+	mu       sync.Mutex
+	out      io.Writer
+	changed  atomic.Int32
+	missing  atomic.Int32 // Commands not found in the baseline
+}
+
+// lines returns the lines of the output without ones matching the ignore patterns.
+func (d *differ) lines(output string) []string {
+	var kept []string
+
+	for _, line := range diff.Lines(output) {
+		ignored := false
+
+		for _, re := range d.ignore {
+			if re.MatchString(line) {
+				ignored = true
+				break
+			}
+		}
+
+		if !ignored {
+			kept = append(kept, line)
+		}
+	}
+
+	return kept
+}
+
+func (d *differ) compare(host, command, output string) error {
+	if d.base == nil {
+		return nil
+	}
+
+	key := newBaselineKey(host, command)
+	name := path.Join(host, key.command)
+
+	base, exists := d.base[key]
+
+	var out string
+	switch {
+	case !exists:
+		// Not a change, e.g. the first run against a new baseline.
+		d.missing.Add(1)
+		out = fmt.Sprintf("Only in b/%s: %s\n", host, key.command)
+	case d.semantic:
+		out = semanticDiff("a/"+name, "b/"+name, d.lines(base), d.lines(output))
+	default:
+		out = diff.Unified("a/"+name, "b/"+name, d.lines(base), d.lines(output), diff.DefaultContext)
+	}
+
+	if out == "" {
+		return nil
+	}
+
+	if exists {
+		d.changed.Add(1)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, err := io.WriteString(d.out, out)
+
+	return err
+}
+
+// semanticDiff returns the changed config blocks, or the unified diff if the output is not a valid config.
+func semanticDiff(aName, bName string, a, b []string) string {
+	aTree, errA := conftree.Parse(strings.Join(a, "\n"))
+	bTree, errB := conftree.Parse(strings.Join(b, "\n"))
+
+	if errA != nil || errB != nil {
+		return diff.Unified(aName, bName, a, b, diff.DefaultContext)
+	}
+
+	changes := conftree.Diff(aTree, bTree)
+	if len(changes) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("--- %s\n+++ %s\n%s", aName, bName, conftree.FormatDiff(changes))
+}
+
+// save stores the output as the baseline for the next runs.
+func (d *differ) save(host, command, output string) error {
+	if d.saveDir == "" {
+		return nil
+	}
+
+	dir := path.Join(d.saveDir, host)
+	if err := os.MkdirAll(dir, baselineDirPerm); err != nil {
+		return fmt.Errorf("cannot save baseline: %w", err)
+	}
+
+	fileName := path.Join(dir, commandFileName(command)+baselineFileExt)
+	if err := os.WriteFile(fileName, []byte(output), baselineFilePerm); err != nil {
+		return fmt.Errorf("cannot save baseline: %w", err)
+	}
+
+	return nil
+}
+
+func newDiffer(source, saveDir string, ignorePatterns []string, semantic bool, out io.Writer) (*differ, error) {
+	d := &differ{
+		semantic: semantic,
+		saveDir:  saveDir,
+		out:      out,
+	}
+
+	for _, pattern := range ignorePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile ignore pattern: %w", err)
+		}
+
+		d.ignore = append(d.ignore, re)
+	}
+
+	if source != "" {
+		var err error
+		if d.base, err = loadBaseline(source); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
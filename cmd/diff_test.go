@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DiffTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *DiffTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *DiffTestSuite) TestLoadBaselineDir() {
+	d := &differ{saveDir: suite.dir}
+	suite.Require().NoError(d.save("10.0.0.1", "sh ip route", "route"))
+	suite.Require().NoError(d.save("10.0.0.2", "sh ver", "version"))
+	suite.Require().NoError(os.WriteFile(path.Join(suite.dir, "README"), []byte("not a host"), 0600))
+	suite.Require().NoError(os.WriteFile(path.Join(suite.dir, "10.0.0.1", "notes.md"), []byte("not a command"), 0600))
+
+	b, err := loadBaseline(suite.dir)
+	suite.Require().NoError(err)
+	suite.Equal(baseline{
+		{host: "10.0.0.1", command: "sh_ip_route"}: "route",
+		{host: "10.0.0.2", command: "sh_ver"}:      "version",
+	}, b)
+
+	_, err = loadBaseline(path.Join(suite.dir, "missing"))
+	suite.ErrorContains(err, "cannot open baseline")
+}
+
+func (suite *DiffTestSuite) TestLoadBaselineJSON() {
+	expected := baseline{
+		{host: "10.0.0.1", command: "sh_ip_route"}: "route",
+		{host: "10.0.0.2", command: "sh_ver"}:      "version",
+	}
+
+	for name, data := range map[string]string{
+		"jsonl": `{"host": "10.0.0.1", "command": "sh ip route", "output": "route"}
+{"host": "10.0.0.2", "command": "sh ver", "output": "version"}
+{"host": "10.0.0.2", "command": "sh run", "output": "", "error": "timeout"}
+{"host": "10.0.0.3", "command": "", "output": "", "error": "connect failed"}
+`,
+		"array": `[
+  {"host": "10.0.0.1", "command": "sh ip route", "output": "route"},
+  {"host": "10.0.0.2", "command": "sh ver", "output": "version"}
+]`,
+	} {
+		b, err := loadBaselineJSON(strings.NewReader(data))
+		suite.Require().NoError(err, name)
+		suite.Equal(expected, b, name)
+	}
+
+	_, err := loadBaselineJSON(strings.NewReader(`{"host": `))
+	suite.ErrorContains(err, "cannot parse baseline")
+
+	fileName := path.Join(suite.dir, "results.jsonl")
+	suite.Require().NoError(os.WriteFile(fileName,
+		[]byte(`{"host": "10.0.0.1", "command": "sh ip route", "output": "route"}`), 0600))
+
+	b, err := loadBaseline(fileName)
+	suite.Require().NoError(err)
+	suite.Equal(baseline{{host: "10.0.0.1", command: "sh_ip_route"}: "route"}, b)
+}
+
+func (suite *DiffTestSuite) TestCompare() {
+	var out bytes.Buffer
+
+	d, err := newDiffer("", "", []string{"^uptime"}, false, &out)
+	suite.Require().NoError(err)
+
+	d.base = baseline{
+		newBaselineKey("10.0.0.1", "sh ver"): "version 1\nuptime 1w\n",
+		newBaselineKey("10.0.0.1", "sh run"): "hostname sw1\n",
+	}
+
+	suite.Require().NoError(d.compare("10.0.0.1", "sh run", "hostname sw1\n"))
+	suite.Empty(out.String())
+
+	suite.Require().NoError(d.compare("10.0.0.1", "sh ver", "version 2\nuptime 2w\n"))
+	suite.Contains(out.String(), "--- a/10.0.0.1/sh_ver\n+++ b/10.0.0.1/sh_ver\n")
+	suite.Contains(out.String(), "-version 1\n+version 2\n")
+	suite.NotContains(out.String(), "uptime")
+	suite.Equal(int32(1), d.changed.Load())
+
+	// Missing from the baseline is not a change.
+	out.Reset()
+	suite.Require().NoError(d.compare("10.0.0.1", "sh ip route", "route"))
+	suite.Require().NoError(d.compare("10.0.0.2", "sh ver", "version 1\n"))
+	suite.Equal("Only in b/10.0.0.1: sh_ip_route\nOnly in b/10.0.0.2: sh_ver\n", out.String())
+	suite.Equal(int32(1), d.changed.Load())
+	suite.Equal(int32(2), d.missing.Load())
+}
+
+func TestDiffTestSuite(t *testing.T) {
+	suite.Run(t, new(DiffTestSuite))
+}
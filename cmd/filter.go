@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/transport"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+hostFilter selects the hosts to run on. A host is selected if it matches every kind of the set filters,
+any value of a kind is enough. Excluded hosts are removed after that.
+*/
+type hostFilter struct {
+	globs   []string
+	regexes []*regexp.Regexp
+	tags    []string
+	groups  []string
+	hosts   map[string]bool // From the hosts file
+	exclude []string
+}
+
+func containsAny(list, values []string) bool {
+	for _, v := range values {
+		for _, item := range list {
+			if item == v {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchGlobs(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *hostFilter) match(hc *config.HostConfig) bool {
+	address := hc.Host.Host
+
+	if len(f.globs) > 0 && !matchGlobs(f.globs, address) {
+		return false
+	}
+
+	if len(f.regexes) > 0 {
+		matched := false
+		for _, re := range f.regexes {
+			matched = matched || re.MatchString(address)
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.tags) > 0 && !containsAny(hc.Tags, f.tags) {
+		return false
+	}
+
+	if len(f.groups) > 0 && !containsAny(hc.Groups, f.groups) {
+		return false
+	}
+
+	if f.hosts != nil && !f.hosts[address] && !f.hosts[hc.Host.GetHostPort()] {
+		return false
+	}
+
+	return !matchGlobs(f.exclude, address)
+}
+
+// readHostsFile reads host addresses or host:port, one per line. Empty lines and # comments are skipped.
+func readHostsFile(fileName string) (map[string]bool, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open hosts file: %w", err)
+	}
+	defer f.Close()
+
+	hosts := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			hosts[line] = true
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read hosts file: %w", err)
+	}
+
+	return hosts, nil
+}
+
+func newHostFilter(globs, regexes, tags, groups []string, hostsFile string, exclude []string) (*hostFilter, error) {
+	f := &hostFilter{
+		globs:   globs,
+		tags:    tags,
+		groups:  groups,
+		exclude: exclude,
+	}
+
+	for _, pattern := range append(append([]string{}, globs...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad host pattern %s: %w", pattern, err)
+		}
+	}
+
+	for _, pattern := range regexes {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile host regex: %w", err)
+		}
+
+		f.regexes = append(f.regexes, re)
+	}
+
+	if hostsFile != "" {
+		var err error
+		if f.hosts, err = readHostsFile(hostsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// listedHost is the effective settings of the host printed with -list-hosts. Passwords are omitted.
+type listedHost struct {
+	Host            string                 `yaml:"host"`
+	Port            int                    `yaml:"port"`
+	Transport       string                 `yaml:"transport"`
+	Username        string                 `yaml:"username,omitempty"`
+	Platform        string                 `yaml:"platform,omitempty"`
+	Groups          []string               `yaml:"groups,omitempty"`
+	Tags            []string               `yaml:"tags,omitempty"`
+	InitialCommands []string               `yaml:"initial_commands"`
+	Commands        []string               `yaml:"commands"`
+	ExitCommand     string                 `yaml:"exit_command"`
+	Vars            map[string]interface{} `yaml:"vars,omitempty"`
+	ConsoleConfig   config.ConsoleConfig   `yaml:"console_config"`
+}
+
+func transportName(tt int) string {
+	switch tt {
+	case transport.TransportSSH:
+		return "ssh"
+	case transport.TransportTELNET:
+		return "telnet"
+	case transport.TransportDummy:
+		return "dummy"
+	}
+
+	return fmt.Sprintf("unknown(%d)", tt)
+}
+
+func listHosts(w io.Writer, hosts []config.HostConfig) error {
+	listed := make([]listedHost, 0, len(hosts))
+
+	for i := range hosts {
+		hc := &hosts[i]
+		listed = append(listed, listedHost{
+			Host:            hc.Host.Host,
+			Port:            hc.Host.Port,
+			Transport:       transportName(hc.Host.TransportType),
+			Username:        hc.Host.Username,
+			Platform:        hc.Platform,
+			Groups:          hc.Groups,
+			Tags:            hc.Tags,
+			InitialCommands: hc.InitialCommands,
+			Commands:        hc.Commands,
+			ExitCommand:     hc.ExitCommand,
+			Vars:            hc.Vars,
+			ConsoleConfig:   hc.ConsoleConfig,
+		})
+	}
+
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+
+	if err := enc.Encode(listed); err != nil {
+		return fmt.Errorf("cannot list hosts: %w", err)
+	}
+
+	return nil
+}
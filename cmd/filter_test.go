@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/host"
+	"github.com/jgivc/console/transport"
+	"github.com/stretchr/testify/suite"
+)
+
+type FilterTestSuite struct {
+	suite.Suite
+	dir   string
+	hosts []config.HostConfig
+}
+
+func (suite *FilterTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+
+	newHost := func(address string, port int, tags, groups []string) config.HostConfig {
+		return config.HostConfig{
+			Host:   host.Host{Host: address, Port: port},
+			Tags:   tags,
+			Groups: groups,
+		}
+	}
+
+	suite.hosts = []config.HostConfig{
+		newHost("core1", 22, []string{"core", "dc1"}, []string{"ios"}),
+		newHost("core2", 2222, []string{"core", "dc2"}, []string{"ios"}),
+		newHost("access1", 22, []string{"access", "dc1"}, []string{"nxos"}),
+		newHost("10.0.0.1", 23, nil, nil),
+	}
+}
+
+func (suite *FilterTestSuite) selected(f *hostFilter) []string {
+	var names []string
+	for i := range suite.hosts {
+		if f.match(&suite.hosts[i]) {
+			names = append(names, suite.hosts[i].Host.Host)
+		}
+	}
+
+	return names
+}
+
+func (suite *FilterTestSuite) TestMatch() {
+	hostsFile := path.Join(suite.dir, "hosts.txt")
+	suite.Require().NoError(os.WriteFile(hostsFile, []byte("# core\ncore1\n\n  core2:2222  \naccess1:2222\n"), 0600))
+
+	for _, tc := range []struct {
+		name                                  string
+		globs, regexes, tags, groups, exclude []string
+		hostsFile                             string
+		expected                              []string
+	}{
+		{name: "none", expected: []string{"core1", "core2", "access1", "10.0.0.1"}},
+		{name: "glob", globs: []string{"core*"}, expected: []string{"core1", "core2"}},
+		{name: "globs or", globs: []string{"core1", "access*"}, expected: []string{"core1", "access1"}},
+		{name: "regex", regexes: []string{`^\d+\.`}, expected: []string{"10.0.0.1"}},
+		{name: "tags or", tags: []string{"dc2", "access"}, expected: []string{"core2", "access1"}},
+		{name: "groups", groups: []string{"nxos"}, expected: []string{"access1"}},
+		{name: "tag and group", tags: []string{"dc1"}, groups: []string{"ios"}, expected: []string{"core1"}},
+		{name: "glob and tag", globs: []string{"core*"}, tags: []string{"access"}},
+		{name: "exclude", tags: []string{"core"}, exclude: []string{"core2"}, expected: []string{"core1"}},
+		{name: "exclude only", exclude: []string{"core*", "10.*"}, expected: []string{"access1"}},
+		{name: "hosts file", hostsFile: hostsFile, expected: []string{"core1", "core2"}},
+		{name: "hosts file and tag", hostsFile: hostsFile, tags: []string{"dc2"}, expected: []string{"core2"}},
+	} {
+		f, err := newHostFilter(tc.globs, tc.regexes, tc.tags, tc.groups, tc.hostsFile, tc.exclude)
+		suite.Require().NoError(err, tc.name)
+		suite.Equal(tc.expected, suite.selected(f), tc.name)
+	}
+}
+
+func (suite *FilterTestSuite) TestNewHostFilter() {
+	_, err := newHostFilter([]string{"[core"}, nil, nil, nil, "", nil)
+	suite.ErrorContains(err, "bad host pattern")
+
+	_, err = newHostFilter(nil, nil, nil, nil, "", []string{"[core"})
+	suite.ErrorContains(err, "bad host pattern")
+
+	_, err = newHostFilter(nil, []string{"("}, nil, nil, "", nil)
+	suite.ErrorContains(err, "cannot compile host regex")
+
+	_, err = newHostFilter(nil, nil, nil, nil, path.Join(suite.dir, "missing"), nil)
+	suite.ErrorContains(err, "cannot open hosts file")
+}
+
+func (suite *FilterTestSuite) TestListHosts() {
+	hc := suite.hosts[0]
+	hc.Host.TransportType = transport.TransportSSH
+	hc.Host.Account = host.Account{Username: "admin", Password: "secret", EnablePassword: "enable-secret"}
+	hc.Commands = []string{"sh ver"}
+
+	var out bytes.Buffer
+	suite.Require().NoError(listHosts(&out, []config.HostConfig{hc}))
+	suite.Contains(out.String(), "host: core1\n")
+	suite.Contains(out.String(), "transport: ssh\n")
+	suite.Contains(out.String(), "username: admin\n")
+	suite.Contains(out.String(), "- sh ver\n")
+	suite.NotContains(out.String(), "secret")
+}
+
+func TestFilterTestSuite(t *testing.T) {
+	suite.Run(t, new(FilterTestSuite))
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -12,10 +13,14 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/jgivc/console"
+	"github.com/jgivc/console/backup"
 	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/extract"
 	"github.com/jgivc/console/host"
+	"github.com/jgivc/console/textfsm"
 	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
 )
@@ -41,12 +46,41 @@ func main() {
 	logDir := flag.String("l", "", "Log dir. Store output to logdir/host_addtess.log")
 	ack := flag.Bool("a", false, "Ack username, password")
 	ackEnable := flag.Bool("A", false, "Ack enable password. Works together with -a")
-	dummy := flag.String("d", "", "Dummy transport config")
+	dummy := flag.String("d", "", "Dummy transport config: scenario file, dir with host.xml and default.xml or yaml map host: file")
 	printConfig := flag.Bool("p", false, "Print default console config and exit.")
+	recordDir := flag.String("r", "", "Record sessions to dir. Store to dir/host_time_pid-seq.format")
+	recordFormat := flag.String("rf", "", "Record format: cast (asciinema v2) or jsonl")
+	captureDir := flag.String("capture", "", "Save sessions to dir/host.xml as dummy transport scenarios")
+	outputFormat := flag.String("o", "", "Output format: jsonl or csv. Raw output is written to log dir only")
+	outputFile := flag.String("of", "", "Write -o output to file instead of stdout")
+	templatesFile := flag.String("t", "", "Yaml map of commands to TextFSM templates. Parsed records are added to -o output, jsonl by default")
+	complianceRules := flag.String("compliance", "", "Check output against compliance rules from yaml file, print pass/fail per host and rule")
+	complianceFile := flag.String("compliance-report", "", "Write -compliance results to file as JSON")
+	summaryFile := flag.String("s", "", "Write per host summary to file as JSON")
+	backupDir := flag.String("backup", "", "Save output of all commands to git repository in dir, one file per host")
+
+	diffSource := flag.String("diff", "", "Compare output with baseline dir or jsonl results file, print unified diffs")
+	diffSemantic := flag.Bool("diff-semantic", false, "Compare -diff output as config trees, ignore the order of sibling lines")
+	saveBaseline := flag.String("save-baseline", "", "Save output to dir/host/command.txt as baseline for -diff")
 
 	var commandFlags commands
 	flag.Var(&commandFlags, "e", "Commands to execute. Multiple values accepted.")
 
+	var ignoreFlags commands
+	flag.Var(&ignoreFlags, "diff-ignore", "Ignore lines matching regex in -diff. Multiple values accepted.")
+
+	var inventoryFlags commands
+	flag.Var(&inventoryFlags, "i", "Load more hosts from inventory file: csv, json, Ansible ini or yaml. Multiple values accepted.")
+
+	var hostGlobs, hostRegexes, tagFlags, groupFlags, excludeFlags commands
+	flag.Var(&hostGlobs, "host", "Run on hosts which address matches glob, e.g. 10.0.1.*. Multiple values accepted.")
+	flag.Var(&hostRegexes, "host-regex", "Run on hosts which address matches regex. Multiple values accepted.")
+	flag.Var(&tagFlags, "tag", "Run on hosts with tag. Multiple values accepted.")
+	flag.Var(&groupFlags, "group", "Run on hosts in group. Multiple values accepted.")
+	flag.Var(&excludeFlags, "exclude", "Skip hosts which address matches glob. Multiple values accepted.")
+	hostsFile := flag.String("hosts-file", "", "Run on hosts listed in file, one address or address:port per line")
+	listHostsOnly := flag.Bool("list-hosts", false, "Print selected hosts with effective settings and exit, passwords are not printed")
+
 	flag.Parse()
 
 	if *printConfig {
@@ -60,9 +94,19 @@ func main() {
 		os.Exit(0)
 	}
 
+	filter, err := newHostFilter(hostGlobs, hostRegexes, tagFlags, groupFlags, *hostsFile, excludeFlags)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	flagsCfg := &config.FromFlags{
-		Commands:    commandFlags,
-		DummyConfig: *dummy,
+		Commands:     commandFlags,
+		DummyConfig:  *dummy,
+		RecordDir:    *recordDir,
+		RecordFormat: *recordFormat,
+		CaptureDir:   *captureDir,
+		Inventory:    inventoryFlags,
+		Filter:       filter.match,
 	}
 
 	if *ack {
@@ -78,14 +122,67 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if len(cfg.Hosts) == 0 {
+		log.Fatal("no hosts selected")
+	}
+
+	if *listHostsOnly {
+		if err = listHosts(os.Stdout, cfg.Hosts); err != nil {
+			log.Fatal(err)
+		}
+
+		os.Exit(0)
+	}
+
 	if *logDir != "" {
 		if errCreateDir := os.Mkdir(*logDir, defaultLogDirPerm); errCreateDir != nil {
 			panic(errCreateDir)
 		}
 	}
 
+	for _, dir := range []string{*recordDir, *captureDir} {
+		if dir == "" {
+			continue
+		}
+
+		if errCreateDir := os.MkdirAll(dir, defaultLogDirPerm); errCreateDir != nil {
+			panic(errCreateDir)
+		}
+	}
+
+	var templates map[string]*textfsm.Template
+	if *templatesFile != "" {
+		if templates, err = loadTemplates(*templatesFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	extractors, err := newExtractors(cfg.Extract)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Records are written to the structured output only.
+	if (templates != nil || extractors != nil) && *outputFormat == "" {
+		*outputFormat = outputFormatJSONL
+	}
+
+	var results resultWriter
+	if *outputFormat != "" {
+		results, err = newResultWriter(*outputFormat, *outputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	var wg sync.WaitGroup
-	logger := log.New(os.Stdout, "", log.LstdFlags)
+
+	// Keep stdout clean for the results.
+	logOut := os.Stdout
+	if (results != nil && *outputFile == "") || *diffSource != "" {
+		logOut = os.Stderr
+	}
+	logger := log.New(logOut, "", log.LstdFlags)
 	ctx, cancel := context.WithCancel(context.Background())
 	ch := make(chan *config.HostConfig)
 
@@ -101,10 +198,46 @@ func main() {
 		cancel()
 	}()
 
+	var repo *backup.Repo
+	if *backupDir != "" {
+		if repo, err = backup.Open(*backupDir, cfg.Backup.VolatilePatterns); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var cmp *differ
+	if *diffSource != "" || *saveBaseline != "" {
+		cmp, err = newDiffer(*diffSource, *saveBaseline, append(cfg.Diff.IgnorePatterns, ignoreFlags...), *diffSemantic, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var report *complianceReport
+	if *complianceRules != "" {
+		if report, err = newComplianceReport(*complianceRules); err != nil {
+			log.Fatal(err)
+		}
+
+		// Collect the output the rules need.
+		for i := range cfg.Hosts {
+			cfg.Hosts[i].Commands = appendMissing(cfg.Hosts[i].Commands, report.rules.Commands())
+		}
+	}
+
+	var sum summary
+
 	for i := 0; i < *workers; i++ {
 		w := worker{
-			logDir: *logDir,
-			logger: logger,
+			logDir:     *logDir,
+			logger:     logger,
+			results:    results,
+			templates:  templates,
+			extractors: extractors,
+			summary:    &sum,
+			compliance: report,
+			backup:     repo,
+			differ:     cmp,
 		}
 
 		wg.Add(1)
@@ -121,6 +254,7 @@ func main() {
 		for i := range cfg.Hosts {
 			select {
 			case <-ctx.Done():
+				sum.skip(cfg.Hosts[i:])
 				return
 			case ch <- &cfg.Hosts[i]:
 			}
@@ -128,6 +262,57 @@ func main() {
 	}()
 
 	wg.Wait()
+
+	if repo != nil {
+		changed, errCommit := repo.Commit(fmt.Sprintf("Backup %s", time.Now().Format(time.RFC3339)))
+		if errCommit != nil {
+			logger.Printf("Cannot commit backup, error: %v", errCommit)
+		} else {
+			logger.Printf("Changed hosts: %d %s", len(changed), strings.Join(changed, " "))
+		}
+	}
+
+	if err = sum.print(logOut); err != nil {
+		logger.Printf("Cannot print summary, error: %v", err)
+	}
+
+	if *summaryFile != "" {
+		if err = sum.writeJSON(*summaryFile); err != nil {
+			logger.Print(err)
+		}
+	}
+
+	if report != nil {
+		if err = report.print(logOut); err != nil {
+			logger.Printf("Cannot print compliance report, error: %v", err)
+		}
+
+		if *complianceFile != "" {
+			if err = report.writeJSON(*complianceFile); err != nil {
+				logger.Print(err)
+			}
+		}
+	}
+
+	if results != nil {
+		results.Close()
+	}
+
+	code := sum.exitCode()
+	if code == 0 && report != nil && report.failed() {
+		code = exitNonCompliant
+	}
+
+	if cmp != nil && cmp.missing.Load() > 0 {
+		logger.Printf("Commands without baseline: %d", cmp.missing.Load())
+	}
+
+	if code == 0 && cmp != nil && cmp.changed.Load() > 0 {
+		logger.Printf("Changed commands: %d", cmp.changed.Load())
+		code = exitChanged
+	}
+
+	os.Exit(code)
 }
 
 func getAccount(ackEnable bool) (*host.Account, error) {
@@ -179,8 +364,40 @@ func getAccount(ackEnable bool) (*host.Account, error) {
 }
 
 type worker struct {
-	logDir string
-	logger *log.Logger
+	logDir     string
+	logger     *log.Logger
+	results    resultWriter
+	templates  map[string]*textfsm.Template
+	extractors map[string]*extract.Extractor
+	summary    *summary
+	compliance *complianceReport
+	backup     *backup.Repo
+	differ     *differ
+}
+
+func (w *worker) writeResult(r *result) {
+	if w.results == nil {
+		return
+	}
+
+	if err := w.results.Write(r); err != nil {
+		w.logger.Printf("Cannot write result for host %s, error: %v", r.Host, err)
+	}
+}
+
+// parse returns the records of the output by the -t template or the extract config of the command.
+func (w *worker) parse(cmd, out string) ([]textfsm.Record, error) {
+	out = console.CommandOutput(cmd, out)
+
+	if t, exists := w.templates[cmd]; exists {
+		return t.Parse(out)
+	}
+
+	if e, exists := w.extractors[cmd]; exists {
+		return e.Extract(out), nil
+	}
+
+	return nil, nil
 }
 
 func (w *worker) Run(ctx context.Context, wg *sync.WaitGroup, ch chan *config.HostConfig) {
@@ -193,47 +410,124 @@ func (w *worker) Run(ctx context.Context, wg *sync.WaitGroup, ch chan *config.Ho
 func (w *worker) run(ctx context.Context, cfg *config.HostConfig) {
 	w.logger.Printf("Get host: %s", cfg.Host.Host)
 
+	outcome := hostOutcome{
+		Host:     cfg.Host.Host,
+		Commands: len(cfg.Commands),
+	}
+
+	start := time.Now()
+	defer func() {
+		outcome.done(start)
+		w.summary.add(outcome)
+	}()
+
 	var (
-		outFile *os.File
+		outFile io.Writer
 		err     error
 	)
 
-	if w.logDir == "" {
-		outFile = os.Stdout
-	} else {
-		outFile, err = os.OpenFile(path.Join(w.logDir, fmt.Sprintf("%s.log", cfg.Host.Host)),
+	switch {
+	case w.logDir != "":
+		f, err2 := os.OpenFile(path.Join(w.logDir, fmt.Sprintf("%s.log", cfg.Host.Host)),
 			os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			w.logger.Printf("Cannot openlog file for host %s, error: %v", cfg.Host.Host, err)
+		if err2 != nil {
+			w.logger.Printf("Cannot openlog file for host %s, error: %v", cfg.Host.Host, err2)
+			outcome.Status = statusConnectFailed
+			outcome.Error = err2.Error()
 			return
 		}
-		defer outFile.Close()
+		defer f.Close()
+
+		outFile = f
+	case w.results != nil || w.backup != nil || w.differ != nil || w.compliance != nil:
+		outFile = io.Discard
+	default:
+		outFile = os.Stdout
 	}
 
-	c := console.NewWithConfig(&cfg.ConsoleConfig)
-	if err2 := c.Open(ctx, &cfg.Host); err2 != nil {
-		w.logger.Printf("Cannot open console to host %s, error: %v", cfg.Host.Host, err2)
-		return
+	opts := console.ReconnectOptionsFromConfig(&cfg.ConsoleConfig)
+	opts.InitialCommands = cfg.InitialCommands
+	opts.OnInitialCommandError = func(cmd string, err error) {
+		w.logger.Printf("Cannot run command: %s on host %s, error: %v", cmd, cfg.Host.Host, err)
+	}
+	opts.OnReconnect = func(ev console.ReconnectEvent) {
+		if ev.Err != nil {
+			w.logger.Printf("Reconnect to host %s failed, attempt: %d, error: %v", ev.Host.Host, ev.Attempt, ev.Err)
+			return
+		}
+		w.logger.Printf("Reconnected to host %s, attempt: %d, cause: %v", ev.Host.Host, ev.Attempt, ev.Cause)
 	}
-	defer c.Close()
 
-	for _, cmd := range cfg.InitialCommands {
-		if err2 := c.Run(cmd); err2 != nil {
-			w.logger.Printf("Cannot run command: %s on host %s, error: %v", cfg.Host.Host, cmd, err2)
+	c := console.NewReconnectConsole(console.NewWithConfig(&cfg.ConsoleConfig), opts)
+	if err = c.Open(ctx, &cfg.Host); err != nil {
+		w.logger.Printf("Cannot open console to host %s, error: %v", cfg.Host.Host, err)
+		w.writeResult(newResult(cfg.Host.Host, "", start, "", fmt.Errorf("cannot open console: %w", err)))
+		outcome.Status = connectStatus(err)
+		outcome.Error = err.Error()
+		outcome.Failed = outcome.Commands
+
+		if w.compliance != nil {
+			w.compliance.skip(cfg.Host.Host, err)
 		}
+
+		return
 	}
+	defer c.Close()
+
+	var backupData strings.Builder
+
+	outputs := make(map[string]string)
 
 	for _, cmd := range cfg.Commands {
+		cmdStart := time.Now()
 		out, err3 := c.Execute(cmd)
+		res := newResult(cfg.Host.Host, cmd, cmdStart, out, err3)
+
+		if err3 == nil {
+			var errParse error
+			if res.Parsed, errParse = w.parse(cmd, out); errParse != nil {
+				w.logger.Printf("Cannot parse output of command: %s from host %s, error: %v", cmd, cfg.Host.Host, errParse)
+			}
+		}
+
+		w.writeResult(res)
+
 		if err3 != nil {
-			w.logger.Printf("Cannot execute command: %s to host %s, error: %v", cfg.Host.Host, cmd, err3)
+			w.logger.Printf("Cannot execute command: %s to host %s, error: %v", cmd, cfg.Host.Host, err3)
+			outcome.failCommand(err3)
 			continue
 		}
 
-		_, errWrite := outFile.WriteString(out)
+		_, errWrite := io.WriteString(outFile, out)
 		if errWrite != nil {
 			w.logger.Printf("Cannot write result to host out file. Host: %s, error: %v", cfg.Host.Host, errWrite)
 		}
+
+		// Without the echo and the prompt, so a prompt change is not a config change.
+		output := console.CommandOutput(cmd, out)
+		backupData.WriteString(output)
+		outputs[cmd] = output
+
+		if w.differ != nil {
+			if errDiff := w.differ.compare(cfg.Host.Host, cmd, out); errDiff != nil {
+				w.logger.Printf("Cannot write diff for host %s, error: %v", cfg.Host.Host, errDiff)
+			}
+
+			if errSave := w.differ.save(cfg.Host.Host, cmd, out); errSave != nil {
+				w.logger.Printf("Cannot save baseline for host %s, error: %v", cfg.Host.Host, errSave)
+			}
+		}
+	}
+
+	if w.compliance != nil {
+		w.compliance.check(cfg.Host.Host, outputs)
+	}
+
+	// Partial output must not replace the previous backup.
+	if w.backup != nil && outcome.Failed == 0 {
+		if errSave := w.backup.Save(cfg.Host.Host, backupData.String()); errSave != nil {
+			w.logger.Printf("Cannot save backup for host %s, error: %v", cfg.Host.Host, errSave)
+		}
 	}
 
 	c.Sendln(cfg.ExitCommand)
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jgivc/console/textfsm"
+)
+
+const (
+	outputFormatJSONL = "jsonl"
+	outputFormatCSV   = "csv"
+
+	outputFilePerm = 0644
+)
+
+var csvHeader = []string{"host", "command", "output", "error", "start", "end", "duration", "parsed"}
+
+// result is the outcome of one command. A host that cannot be connected gives one result with empty command.
+type result struct {
+	Host     string           `json:"host"`
+	Command  string           `json:"command"`
+	Output   string           `json:"output"`
+	Error    string           `json:"error,omitempty"`
+	Start    time.Time        `json:"start"`
+	End      time.Time        `json:"end"`
+	Duration float64          `json:"duration"`         // Seconds
+	Parsed   []textfsm.Record `json:"parsed,omitempty"` // Records of the -t template of the command
+}
+
+func newResult(host, command string, start time.Time, output string, err error) *result {
+	end := time.Now()
+
+	r := &result{
+		Host:     host,
+		Command:  command,
+		Output:   output,
+		Start:    start,
+		End:      end,
+		Duration: end.Sub(start).Seconds(),
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	return r
+}
+
+// resultWriter writes the results of all workers to one stream.
+type resultWriter interface {
+	Write(r *result) error
+	Close() error
+}
+
+type jsonlWriter struct {
+	mu  sync.Mutex
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+func (w *jsonlWriter) Write(r *result) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.enc.Encode(r)
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.w.Close()
+}
+
+type csvWriter struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+	cw *csv.Writer
+}
+
+func (w *csvWriter) Write(r *result) error {
+	var parsed string
+	if r.Parsed != nil {
+		data, err := json.Marshal(r.Parsed)
+		if err != nil {
+			return err
+		}
+
+		parsed = string(data)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.cw.Write([]string{
+		r.Host,
+		r.Command,
+		r.Output,
+		r.Error,
+		r.Start.Format(time.RFC3339Nano),
+		r.End.Format(time.RFC3339Nano),
+		strconv.FormatFloat(r.Duration, 'f', -1, 64),
+		parsed,
+	}); err != nil {
+		return err
+	}
+
+	w.cw.Flush()
+
+	return w.cw.Error()
+}
+
+func (w *csvWriter) Close() error {
+	return w.w.Close()
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// newResultWriter returns the writer of the format to fileName, or to stdout if fileName is empty.
+func newResultWriter(format, fileName string) (resultWriter, error) {
+	var w io.WriteCloser = nopCloser{os.Stdout}
+
+	if format != outputFormatJSONL && format != outputFormatCSV {
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+
+	if fileName != "" {
+		f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, outputFilePerm)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create output file: %w", err)
+		}
+
+		w = f
+	}
+
+	if format == outputFormatJSONL {
+		return &jsonlWriter{w: w, enc: json.NewEncoder(w)}, nil
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("cannot write output: %w", err)
+	}
+
+	return &csvWriter{w: w, cw: cw}, nil
+}
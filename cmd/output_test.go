@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgivc/console/textfsm"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutputTestSuite struct {
+	suite.Suite
+	dir     string
+	results []*result
+}
+
+func (suite *OutputTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ok := newResult("10.0.0.1", "sh ver", start, "Version 15.2\nsw1#", nil)
+	ok.Parsed = []textfsm.Record{{"VERSION": "15.2"}}
+
+	suite.results = []*result{
+		ok,
+		newResult("10.0.0.2", "", start, "", errors.New("connection refused")),
+	}
+}
+
+func (suite *OutputTestSuite) write(format string) string {
+	fileName := path.Join(suite.dir, "results."+format)
+
+	w, err := newResultWriter(format, fileName)
+	suite.Require().NoError(err)
+
+	for _, r := range suite.results {
+		suite.Require().NoError(w.Write(r))
+	}
+
+	suite.Require().NoError(w.Close())
+
+	data, err := os.ReadFile(fileName)
+	suite.Require().NoError(err)
+
+	return string(data)
+}
+
+func (suite *OutputTestSuite) TestJSONL() {
+	lines := strings.Split(strings.TrimSuffix(suite.write(outputFormatJSONL), "\n"), "\n")
+	suite.Require().Len(lines, 2)
+
+	for i, line := range lines {
+		var r result
+		suite.Require().NoError(json.Unmarshal([]byte(line), &r))
+		suite.Equal(suite.results[i].Host, r.Host)
+		suite.Equal(suite.results[i].Command, r.Command)
+		suite.Equal(suite.results[i].Output, r.Output)
+		suite.Equal(suite.results[i].Error, r.Error)
+		suite.True(suite.results[i].Start.Equal(r.Start))
+	}
+
+	suite.Contains(lines[0], `"parsed":[{"VERSION":"15.2"}]`)
+	suite.NotContains(lines[0], `"error"`)
+	suite.Contains(lines[1], `"error":"connection refused"`)
+	suite.NotContains(lines[1], `"parsed"`)
+
+	// The output is readable as the baseline.
+	b, err := loadBaselineJSON(strings.NewReader(strings.Join(lines, "\n")))
+	suite.Require().NoError(err)
+	suite.Equal(baseline{newBaselineKey("10.0.0.1", "sh ver"): "Version 15.2\nsw1#"}, b)
+}
+
+func (suite *OutputTestSuite) TestCSV() {
+	records, err := csv.NewReader(strings.NewReader(suite.write(outputFormatCSV))).ReadAll()
+	suite.Require().NoError(err)
+	suite.Require().Len(records, 3)
+
+	suite.Equal(csvHeader, records[0])
+	suite.Equal([]string{"10.0.0.1", "sh ver", "Version 15.2\nsw1#", ""}, records[1][:4])
+	suite.Equal("2024-01-02T03:04:05Z", records[1][4])
+	suite.Equal(`[{"VERSION":"15.2"}]`, records[1][7])
+	suite.Equal([]string{"10.0.0.2", "", "", "connection refused"}, records[2][:4])
+	suite.Empty(records[2][7])
+}
+
+func (suite *OutputTestSuite) TestUnknownFormat() {
+	_, err := newResultWriter("xml", "")
+	suite.ErrorContains(err, "unknown output format")
+
+	_, err = newResultWriter(outputFormatCSV, path.Join(suite.dir, "missing", "results.csv"))
+	suite.ErrorContains(err, "cannot create output file")
+}
+
+func TestOutputTestSuite(t *testing.T) {
+	suite.Run(t, new(OutputTestSuite))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jgivc/console"
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/util"
+)
+
+const (
+	statusOK             = "ok"
+	statusCommandsFailed = "commands_failed"
+	statusAuthFailed     = "auth_failed"
+	statusTimeout        = "timeout"
+	statusConnectFailed  = "connect_failed"
+	statusSkipped        = "skipped" // Not run as the run was interrupted
+
+	exitPartialFailure = 2 // Some hosts or commands failed
+	exitTotalFailure   = 3 // No command succeeded on any host
+)
+
+// hostOutcome is the outcome of the run on one host.
+type hostOutcome struct {
+	Host     string  `json:"host"`
+	Status   string  `json:"status"`
+	Commands int     `json:"commands"`
+	Failed   int     `json:"failed"`
+	Error    string  `json:"error,omitempty"` // Connect error or the first command error
+	Duration float64 `json:"duration"`        // Seconds
+}
+
+func (o *hostOutcome) failCommand(err error) {
+	o.Failed++
+	if o.Error == "" {
+		o.Error = err.Error()
+	}
+}
+
+func (o *hostOutcome) done(start time.Time) {
+	o.Duration = time.Since(start).Seconds()
+
+	if o.Status == "" {
+		o.Status = statusOK
+		if o.Failed > 0 {
+			o.Status = statusCommandsFailed
+		}
+	}
+}
+
+// connectStatus classifies the error of opening the console.
+func connectStatus(err error) string {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, console.ErrAuthFailed), errors.Is(err, console.ErrEnableFailed):
+		return statusAuthFailed
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return statusTimeout
+	case errors.Is(err, util.ErrNoPromptFound) && !errors.Is(err, util.ErrConnectionLost):
+		return statusTimeout
+	}
+
+	return statusConnectFailed
+}
+
+type summary struct {
+	mu       sync.Mutex
+	outcomes []hostOutcome
+}
+
+func (s *summary) add(o hostOutcome) {
+	s.mu.Lock()
+	s.outcomes = append(s.outcomes, o)
+	s.mu.Unlock()
+}
+
+// skip adds the hosts which were not run, so an interrupted run is not reported as succeeded.
+func (s *summary) skip(hosts []config.HostConfig) {
+	for i := range hosts {
+		s.add(hostOutcome{
+			Host:     hosts[i].Host.Host,
+			Status:   statusSkipped,
+			Commands: len(hosts[i].Commands),
+			Failed:   len(hosts[i].Commands),
+			Error:    "interrupted",
+		})
+	}
+}
+
+func (s *summary) sorted() []hostOutcome {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	outcomes := append([]hostOutcome(nil), s.outcomes...)
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].Host < outcomes[j].Host
+	})
+
+	return outcomes
+}
+
+func (s *summary) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tSTATUS\tFAILED\tDURATION\tERROR")
+
+	for _, o := range s.sorted() {
+		fmt.Fprintf(tw, "%s\t%s\t%d of %d\t%.1fs\t%s\n", o.Host, o.Status, o.Failed, o.Commands, o.Duration, o.Error)
+	}
+
+	return tw.Flush()
+}
+
+func (s *summary) writeJSON(fileName string) error {
+	b, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal summary: %w", err)
+	}
+
+	if err2 := os.WriteFile(fileName, b, outputFilePerm); err2 != nil {
+		return fmt.Errorf("cannot write summary: %w", err2)
+	}
+
+	return nil
+}
+
+// exitCode returns 0 if every command on every host succeeded, exitTotalFailure
+// if no command succeeded at all and exitPartialFailure otherwise.
+func (s *summary) exitCode() int {
+	outcomes := s.sorted()
+	failed, succeeded := 0, 0
+
+	for _, o := range outcomes {
+		if o.Status != statusOK {
+			failed++
+		}
+
+		if o.Status == statusOK || o.Status == statusCommandsFailed {
+			succeeded += o.Commands - o.Failed
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return 0
+	case succeeded == 0:
+		return exitTotalFailure
+	}
+
+	return exitPartialFailure
+}
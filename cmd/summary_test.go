@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jgivc/console"
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/host"
+	"github.com/jgivc/console/util"
+	"github.com/stretchr/testify/suite"
+)
+
+type SummaryTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SummaryTestSuite) TestConnectStatus() {
+	for err, status := range map[error]string{
+		console.ErrAuthFailed:                           statusAuthFailed,
+		fmt.Errorf("open: %w", console.ErrEnableFailed): statusAuthFailed,
+		context.DeadlineExceeded:                        statusTimeout,
+		os.ErrDeadlineExceeded:                          statusTimeout,
+		&net.DNSError{IsTimeout: true}:                  statusTimeout,
+		util.ErrNoPromptFound:                           statusTimeout,
+		fmt.Errorf("%w: %w", util.ErrNoPromptFound, util.ErrConnectionLost): statusConnectFailed,
+		&net.OpError{Op: "dial", Err: errors.New("connection refused")}:     statusConnectFailed,
+	} {
+		suite.Equal(status, connectStatus(err), err.Error())
+	}
+}
+
+func (suite *SummaryTestSuite) TestExitCode() {
+	for _, tc := range []struct {
+		name     string
+		outcomes []hostOutcome
+		expected int
+	}{
+		{name: "no hosts", expected: 0},
+		{
+			name:     "ok",
+			outcomes: []hostOutcome{{Status: statusOK, Commands: 2}, {Status: statusOK, Commands: 1}},
+			expected: 0,
+		},
+		{
+			name:     "host failed",
+			outcomes: []hostOutcome{{Status: statusOK, Commands: 2}, {Status: statusTimeout}},
+			expected: exitPartialFailure,
+		},
+		{
+			name:     "command failed",
+			outcomes: []hostOutcome{{Status: statusCommandsFailed, Commands: 2, Failed: 1}},
+			expected: exitPartialFailure,
+		},
+		{
+			name:     "all commands failed",
+			outcomes: []hostOutcome{{Status: statusCommandsFailed, Commands: 2, Failed: 2}, {Status: statusAuthFailed}},
+			expected: exitTotalFailure,
+		},
+		{
+			name:     "all hosts failed",
+			outcomes: []hostOutcome{{Status: statusConnectFailed}, {Status: statusTimeout}},
+			expected: exitTotalFailure,
+		},
+	} {
+		var s summary
+		for _, o := range tc.outcomes {
+			s.add(o)
+		}
+
+		suite.Equal(tc.expected, s.exitCode(), tc.name)
+	}
+}
+
+func (suite *SummaryTestSuite) TestSkip() {
+	var s summary
+	s.add(hostOutcome{Host: "sw1", Status: statusOK, Commands: 1})
+	s.skip([]config.HostConfig{
+		{Host: host.Host{Host: "sw2"}, Commands: []string{"sh ver", "sh run"}},
+		{Host: host.Host{Host: "sw3"}},
+	})
+
+	// The interrupted run is not a success.
+	suite.Equal(exitPartialFailure, s.exitCode())
+
+	outcomes := s.sorted()
+	suite.Require().Len(outcomes, 3)
+	suite.Equal(hostOutcome{Host: "sw2", Status: statusSkipped, Commands: 2, Failed: 2, Error: "interrupted"}, outcomes[1])
+	suite.Equal(statusSkipped, outcomes[2].Status)
+}
+
+func (suite *SummaryTestSuite) TestPrint() {
+	var s summary
+
+	o := hostOutcome{Host: "sw2", Commands: 2}
+	o.failCommand(errors.New("invalid input"))
+	o.failCommand(errors.New("timeout"))
+	o.done(time.Now())
+	s.add(o)
+
+	o = hostOutcome{Host: "sw1", Commands: 1}
+	o.done(time.Now())
+	s.add(o)
+
+	var out bytes.Buffer
+	suite.Require().NoError(s.print(&out))
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	suite.Require().Len(lines, 3)
+	suite.Contains(string(lines[1]), "sw1")
+	suite.Contains(string(lines[1]), statusOK)
+	suite.Contains(string(lines[2]), statusCommandsFailed)
+	suite.Contains(string(lines[2]), "2 of 2")
+	suite.Contains(string(lines[2]), "invalid input")
+}
+
+func TestSummaryTestSuite(t *testing.T) {
+	suite.Run(t, new(SummaryTestSuite))
+}
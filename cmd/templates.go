@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/extract"
+	"github.com/jgivc/console/textfsm"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+loadTemplates loads the yaml map of commands to TextFSM template files:
+
+	show ip interface brief: templates/cisco_ios_show_ip_interface_brief.textfsm
+	show version: /usr/share/templates/cisco_ios_show_version.textfsm
+
+Relative paths are resolved against the directory of the map file.
+*/
+func loadTemplates(fileName string) (map[string]*textfsm.Template, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read templates: %w", err)
+	}
+
+	var files map[string]string
+	if err = yaml.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("cannot parse templates: %w", err)
+	}
+
+	templates := make(map[string]*textfsm.Template, len(files))
+
+	for cmd, file := range files {
+		if !path.IsAbs(file) {
+			file = path.Join(path.Dir(fileName), file)
+		}
+
+		t, err2 := textfsm.ParseFile(file)
+		if err2 != nil {
+			return nil, err2
+		}
+
+		templates[cmd] = t
+	}
+
+	return templates, nil
+}
+
+// newExtractors compiles the extract config. It returns nil if nothing is configured.
+func newExtractors(cfg config.ExtractConfig) (map[string]*extract.Extractor, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+
+	extractors := make(map[string]*extract.Extractor, len(cfg))
+
+	for cmd := range cfg {
+		spec := cfg[cmd]
+
+		e, err := extract.New(&spec)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile extract for command %s: %w", cmd, err)
+		}
+
+		extractors[cmd] = e
+	}
+
+	return extractors, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/extract"
+	"github.com/jgivc/console/textfsm"
+	"github.com/stretchr/testify/suite"
+)
+
+// showIPIntBrief is the output as returned by Execute: the command echo, the table and the prompt.
+const showIPIntBrief = "show ip interface brief\r\n" +
+	"Interface              IP-Address      OK? Method Status                Protocol\r\n" +
+	"GigabitEthernet0/0     10.0.0.1        YES NVRAM  up                    up\r\n" +
+	"GigabitEthernet0/1     unassigned      YES unset  administratively down down\r\n" +
+	"sw1#"
+
+type TemplatesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TemplatesTestSuite) TestExtractColumns() {
+	extractors, err := newExtractors(config.ExtractConfig{
+		"show ip interface brief": {Columns: &extract.Columns{}},
+	})
+	suite.Require().NoError(err)
+
+	w := &worker{extractors: extractors}
+
+	records, err := w.parse("show ip interface brief", showIPIntBrief)
+	suite.Require().NoError(err)
+	suite.Equal([]textfsm.Record{
+		{"Interface": "GigabitEthernet0/0", "IP-Address": "10.0.0.1", "OK?": "YES", "Method": "NVRAM",
+			"Status": "up", "Protocol": "up"},
+		{"Interface": "GigabitEthernet0/1", "IP-Address": "unassigned", "OK?": "YES", "Method": "unset",
+			"Status": "administratively down", "Protocol": "down"},
+	}, records)
+
+	records, err = w.parse("sh clock", "sh clock\r\n10:00:00\r\nsw1#")
+	suite.NoError(err)
+	suite.Nil(records)
+}
+
+func (suite *TemplatesTestSuite) TestTemplate() {
+	fileName := path.Join(suite.T().TempDir(), "templates.yml")
+	abs, err := filepath.Abs("../textfsm/testdata/cisco_ios_show_ip_interface_brief.textfsm")
+	suite.Require().NoError(err)
+	suite.Require().NoError(os.WriteFile(fileName, []byte("show ip interface brief: "+abs+"\n"), 0600))
+
+	templates, err := loadTemplates(fileName)
+	suite.Require().NoError(err)
+
+	// The template ends with ^. -> Error, the echo and the prompt are not parsed.
+	w := &worker{templates: templates}
+
+	records, err := w.parse("show ip interface brief", showIPIntBrief)
+	suite.Require().NoError(err)
+	suite.Equal([]textfsm.Record{
+		{"INTF": "GigabitEthernet0/0", "IPADDR": "10.0.0.1", "STATUS": "up", "PROTO": "up"},
+		{"INTF": "GigabitEthernet0/1", "IPADDR": "unassigned", "STATUS": "administratively down", "PROTO": "down"},
+	}, records)
+}
+
+func TestTemplatesTestSuite(t *testing.T) {
+	suite.Run(t, new(TemplatesTestSuite))
+}
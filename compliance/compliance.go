@@ -0,0 +1,265 @@
+// Package compliance checks command output against rules.
+package compliance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jgivc/console/conftree"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	StatusPass  = "pass"
+	StatusFail  = "fail"
+	StatusError = "error" // The output of the rule command was not collected
+)
+
+var ErrRules = errors.New("invalid compliance rules")
+
+/*
+Rule is checked against the output of Command, or all collected output if Command is empty.
+Every set check must hold:
+
+	contains      a line contains the string
+	not_contains  no line contains the string
+	regex         a line matches the regex
+	not_regex     no line matches the regex
+
+If Section is set, the checks apply to the lines under every config line matching the Section regex,
+e.g. section: '^line vty' and contains: 'transport input ssh'. The config is parsed with conftree,
+so the lines are trimmed and Junos style blocks are supported too.
+*/
+type Rule struct {
+	Name        string `yaml:"name"`
+	Command     string `yaml:"command"`
+	Section     string `yaml:"section"`
+	Contains    string `yaml:"contains"`
+	NotContains string `yaml:"not_contains"`
+	Regex       string `yaml:"regex"`
+	NotRegex    string `yaml:"not_regex"`
+
+	section  *regexp.Regexp
+	regex    *regexp.Regexp
+	notRegex *regexp.Regexp
+}
+
+// Result is the outcome of one rule.
+type Result struct {
+	Rule    string `json:"rule"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"` // Why the rule failed
+}
+
+func compile(rule, name, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: rule %s: %s: %w", ErrRules, rule, name, err)
+	}
+
+	return re, nil
+}
+
+func (r *Rule) compile() (err error) {
+	if r.Name == "" {
+		return fmt.Errorf("%w: rule without name", ErrRules)
+	}
+
+	if r.Contains == "" && r.NotContains == "" && r.Regex == "" && r.NotRegex == "" {
+		return fmt.Errorf("%w: rule %s has no checks", ErrRules, r.Name)
+	}
+
+	if r.section, err = compile(r.Name, "section", r.Section); err != nil {
+		return err
+	}
+
+	if r.regex, err = compile(r.Name, "regex", r.Regex); err != nil {
+		return err
+	}
+
+	r.notRegex, err = compile(r.Name, "not_regex", r.NotRegex)
+
+	return err
+}
+
+// check returns the reasons the lines break the rule.
+func (r *Rule) check(lines []string) []string {
+	var (
+		contains, matches bool
+		reasons           []string
+	)
+
+	for _, line := range lines {
+		if r.Contains != "" && strings.Contains(line, r.Contains) {
+			contains = true
+		}
+
+		if r.NotContains != "" && strings.Contains(line, r.NotContains) {
+			reasons = append(reasons, fmt.Sprintf("found %q", strings.TrimSpace(line)))
+		}
+
+		if r.regex != nil && r.regex.MatchString(line) {
+			matches = true
+		}
+
+		if r.notRegex != nil && r.notRegex.MatchString(line) {
+			reasons = append(reasons, fmt.Sprintf("found %q", strings.TrimSpace(line)))
+		}
+	}
+
+	if r.Contains != "" && !contains {
+		reasons = append(reasons, fmt.Sprintf("missing %q", r.Contains))
+	}
+
+	if r.regex != nil && !matches {
+		reasons = append(reasons, fmt.Sprintf("no line matches %q", r.Regex))
+	}
+
+	return reasons
+}
+
+// checkSections checks the lines under every config line matching the section regex at any level.
+func (r *Rule) checkSections(out string) []string {
+	root, err := conftree.Parse(out)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var sections []*conftree.Node
+
+	root.Walk(func(node *conftree.Node) {
+		if r.section.MatchString(node.Line) {
+			sections = append(sections, node)
+		}
+	})
+
+	if len(sections) == 0 {
+		return []string{fmt.Sprintf("no section matches %q", r.Section)}
+	}
+
+	var reasons []string
+
+	for _, section := range sections {
+		for _, reason := range r.check(section.Lines()) {
+			reasons = append(reasons, section.Line+": "+reason)
+		}
+	}
+
+	return reasons
+}
+
+func (r *Rule) evaluate(out string) Result {
+	var reasons []string
+
+	if r.section == nil {
+		reasons = r.check(strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n"))
+	} else {
+		reasons = r.checkSections(out)
+	}
+
+	if len(reasons) > 0 {
+		return Result{Rule: r.Name, Status: StatusFail, Message: strings.Join(reasons, "; ")}
+	}
+
+	return Result{Rule: r.Name, Status: StatusPass}
+}
+
+type Rules struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Commands returns the commands whose output the rules check.
+func (r *Rules) Commands() []string {
+	var commands []string
+
+	seen := make(map[string]bool)
+	for i := range r.Rules {
+		if cmd := r.Rules[i].Command; cmd != "" && !seen[cmd] {
+			seen[cmd] = true
+			commands = append(commands, cmd)
+		}
+	}
+
+	return commands
+}
+
+/*
+Check evaluates the rules against the outputs of one host, keyed by command.
+A rule which command has no output gives StatusError.
+*/
+func (r *Rules) Check(outputs map[string]string) []Result {
+	results := make([]Result, 0, len(r.Rules))
+
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+
+		if rule.Command == "" {
+			results = append(results, rule.evaluate(joinOutputs(outputs)))
+			continue
+		}
+
+		out, exists := outputs[rule.Command]
+		if !exists {
+			results = append(results, Result{
+				Rule:    rule.Name,
+				Status:  StatusError,
+				Message: fmt.Sprintf("no output of command %q", rule.Command),
+			})
+
+			continue
+		}
+
+		results = append(results, rule.evaluate(out))
+	}
+
+	return results
+}
+
+func joinOutputs(outputs map[string]string) string {
+	commands := make([]string, 0, len(outputs))
+	for cmd := range outputs {
+		commands = append(commands, cmd)
+	}
+
+	sort.Strings(commands)
+
+	var sb strings.Builder
+	for _, cmd := range commands {
+		sb.WriteString(outputs[cmd])
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func Parse(data []byte) (*Rules, error) {
+	var r Rules
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRules, err)
+	}
+
+	for i := range r.Rules {
+		if err := r.Rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
+
+func Load(fileName string) (*Rules, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read compliance rules: %w", err)
+	}
+
+	return Parse(data)
+}
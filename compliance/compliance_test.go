@@ -0,0 +1,94 @@
+package compliance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const showRun = `hostname sw1
+service password-encryption
+enable secret 5 $1$abc
+snmp-server community public RO
+ntp server 10.0.0.100
+line con 0
+ logging synchronous
+line vty 0 4
+ transport input ssh
+line vty 5 15
+ transport input telnet ssh
+ login local
+end
+`
+
+type ComplianceTestSuite struct {
+	suite.Suite
+	rules *Rules
+}
+
+func (suite *ComplianceTestSuite) SetupTest() {
+	var err error
+	suite.rules, err = Load("testdata/rules.yml")
+	suite.Require().NoError(err)
+}
+
+func (suite *ComplianceTestSuite) TestCommands() {
+	suite.Equal([]string{"sh run", "sh ver"}, suite.rules.Commands())
+}
+
+func (suite *ComplianceTestSuite) TestCheck() {
+	suite.Equal([]Result{
+		{Rule: "password encryption", Status: StatusPass},
+		{Rule: "no public community", Status: StatusFail, Message: `found "snmp-server community public RO"`},
+		{Rule: "enable secret", Status: StatusPass},
+		{Rule: "vty ssh only", Status: StatusFail, Message: `line vty 5 15: missing "transport input ssh"`},
+		{Rule: "ntp server", Status: StatusPass},
+		{Rule: "ios version", Status: StatusError, Message: `no output of command "sh ver"`},
+	}, suite.rules.Check(map[string]string{"sh run": showRun}))
+}
+
+func (suite *ComplianceTestSuite) TestSection() {
+	rules, err := Parse([]byte(`rules:
+  - name: aaa
+    section: '^aaa'
+    contains: x
+  - name: con
+    section: '^line con'
+    not_regex: 'synchronous$'
+`))
+	suite.Require().NoError(err)
+
+	suite.Equal([]Result{
+		{Rule: "aaa", Status: StatusFail, Message: `no section matches "^aaa"`},
+		{Rule: "con", Status: StatusFail, Message: `line con 0: found "logging synchronous"`},
+	}, rules.Check(map[string]string{"sh run": showRun}))
+}
+
+func (suite *ComplianceTestSuite) TestBraceSection() {
+	rules, err := Parse([]byte(`rules:
+  - name: ssh
+    section: '^services$'
+    contains: ssh
+`))
+	suite.Require().NoError(err)
+
+	suite.Equal([]Result{{Rule: "ssh", Status: StatusFail, Message: `services: missing "ssh"`}},
+		rules.Check(map[string]string{"show configuration": "system {\n    services {\n        telnet;\n    }\n}\n"}))
+}
+
+func (suite *ComplianceTestSuite) TestBadRules() {
+	for _, data := range []string{
+		"rules: x",
+		"rules:\n  - contains: x\n",
+		"rules:\n  - name: a\n",
+		"rules:\n  - name: a\n    regex: '('\n",
+		"rules:\n  - name: a\n    contains: x\n    section: '('\n",
+	} {
+		_, err := Parse([]byte(data))
+		suite.ErrorIs(err, ErrRules, data)
+	}
+}
+
+func TestComplianceTestSuite(t *testing.T) {
+	suite.Run(t, new(ComplianceTestSuite))
+}
@@ -0,0 +1,432 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jgivc/console/host"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ansibleAll       = "all"
+	ansibleUngrouped = "ungrouped"
+)
+
+type (
+	ansibleGroup struct {
+		hosts    []string
+		vars     map[string]interface{}
+		children []string
+	}
+
+	// ansibleInventory is the parsed INI or YAML inventory.
+	ansibleInventory struct {
+		hosts    []string // In the order of the first appearance
+		hostVars map[string]map[string]interface{}
+		groups   map[string]*ansibleGroup
+	}
+
+	// ansibleYAMLGroup is the group in the YAML inventory.
+	ansibleYAMLGroup struct {
+		Hosts    map[string]map[string]interface{} `yaml:"hosts"`
+		Vars     map[string]interface{}            `yaml:"vars"`
+		Children map[string]*ansibleYAMLGroup      `yaml:"children"`
+	}
+)
+
+func newAnsibleInventory() *ansibleInventory {
+	return &ansibleInventory{
+		hostVars: make(map[string]map[string]interface{}),
+		groups:   make(map[string]*ansibleGroup),
+	}
+}
+
+func (inv *ansibleInventory) group(name string) *ansibleGroup {
+	g, exists := inv.groups[name]
+	if !exists {
+		g = &ansibleGroup{vars: make(map[string]interface{})}
+		inv.groups[name] = g
+	}
+
+	return g
+}
+
+func (inv *ansibleInventory) addHost(group, name string, vars map[string]interface{}) {
+	if _, exists := inv.hostVars[name]; !exists {
+		inv.hosts = append(inv.hosts, name)
+		inv.hostVars[name] = make(map[string]interface{})
+	}
+
+	for k, v := range vars {
+		inv.hostVars[name][k] = v
+	}
+
+	g := inv.group(group)
+	if !hasTag(g.hosts, name) {
+		g.hosts = append(g.hosts, name)
+	}
+}
+
+// depth returns the length of the longest path from the group to the top, as Ansible orders group vars.
+func (inv *ansibleInventory) depth(name string, parents map[string][]string, visited map[string]bool) (int, error) {
+	if visited[name] {
+		return 0, fmt.Errorf("%w: group %s is a child of itself", ErrInventory, name)
+	}
+
+	visited[name] = true
+	defer delete(visited, name)
+
+	d := 0
+	if name != ansibleAll {
+		d = 1
+	}
+
+	for _, parent := range parents[name] {
+		pd, err := inv.depth(parent, parents, visited)
+		if err != nil {
+			return 0, err
+		}
+
+		if pd+1 > d {
+			d = pd + 1
+		}
+	}
+
+	return d, nil
+}
+
+/*
+hostConfigs converts the inventory to hosts:
+
+	ansible_host                        address, the inventory name if not set
+	ansible_port                        port
+	ansible_connection                  telnet for telnet, ssh otherwise
+	ansible_user, ansible_password      account, ansible_ssh_pass is also accepted
+	ansible_become_password             enable password
+	ansible_network_os                  platform, the collection prefix is dropped: cisco.ios.ios is ios
+
+The other vars are host vars, the inventory name is inventory_hostname. The groups of the host
+are its tags, so config groups with the same tags are applied.
+*/
+func (inv *ansibleInventory) hostConfigs() ([]HostConfig, error) {
+	parents := make(map[string][]string)
+	for name, g := range inv.groups {
+		for _, child := range g.children {
+			parents[child] = append(parents[child], name)
+		}
+	}
+
+	hostGroups := make(map[string][]string)
+	for name, g := range inv.groups {
+		for _, h := range g.hosts {
+			hostGroups[h] = append(hostGroups[h], name)
+		}
+	}
+
+	depths := make(map[string]int, len(inv.groups))
+	for name := range inv.groups {
+		d, err := inv.depth(name, parents, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+
+		depths[name] = d
+	}
+
+	hosts := make([]HostConfig, 0, len(inv.hosts))
+
+	for _, name := range inv.hosts {
+		// All groups of the host with the parents.
+		member := make(map[string]bool)
+		queue := append([]string{ansibleAll}, hostGroups[name]...)
+
+		for len(queue) > 0 {
+			g := queue[0]
+			queue = queue[1:]
+
+			if !member[g] {
+				member[g] = true
+				queue = append(queue, parents[g]...)
+			}
+		}
+
+		groups := make([]string, 0, len(member))
+		for g := range member {
+			groups = append(groups, g)
+		}
+
+		sort.Slice(groups, func(i, j int) bool {
+			if depths[groups[i]] != depths[groups[j]] {
+				return depths[groups[i]] < depths[groups[j]]
+			}
+
+			return groups[i] < groups[j]
+		})
+
+		vars := make(map[string]interface{})
+		hc := HostConfig{ConsoleConfig: *DefaultConsoleConfig()}
+
+		for _, g := range groups {
+			if group, exists := inv.groups[g]; exists {
+				for k, v := range group.vars {
+					vars[k] = v
+				}
+			}
+
+			if g != ansibleAll && g != ansibleUngrouped {
+				hc.Tags = append(hc.Tags, g)
+			}
+		}
+
+		for k, v := range inv.hostVars[name] {
+			vars[k] = v
+		}
+
+		hc.setAnsibleVars(name, vars)
+		hosts = append(hosts, hc)
+	}
+
+	return hosts, nil
+}
+
+func (c *HostConfig) setAnsibleVars(name string, vars map[string]interface{}) {
+	var (
+		address = name
+		port    string
+		scheme  = "ssh"
+		account host.Account
+	)
+
+	c.Vars = map[string]interface{}{"inventory_hostname": name}
+
+	for k, v := range vars {
+		s := fmt.Sprint(v)
+
+		switch k {
+		case "ansible_host":
+			address = s
+		case "ansible_port":
+			port = s
+		case "ansible_connection":
+			if s == "telnet" {
+				scheme = "telnet"
+			}
+		case "ansible_user":
+			account.Username = s
+		case "ansible_password", "ansible_ssh_pass":
+			account.Password = s
+		case "ansible_become_password", "ansible_become_pass":
+			account.EnablePassword = s
+		case "ansible_network_os":
+			c.Platform = s[strings.LastIndex(s, ".")+1:]
+		default:
+			c.Vars[k] = v
+		}
+	}
+
+	c.URI = address
+	if port != "" {
+		c.URI += ":" + port
+	}
+
+	c.URI = scheme + "://" + c.URI
+
+	if account != (host.Account{}) {
+		c.Account = &account
+	}
+}
+
+// iniFields splits the line by spaces, quoted values can contain spaces.
+func iniFields(line string) ([]string, error) {
+	var (
+		fields []string
+		sb     strings.Builder
+		quote  rune
+		inside bool
+	)
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				sb.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inside = true
+		case r == ' ' || r == '\t':
+			if inside {
+				fields = append(fields, sb.String())
+				sb.Reset()
+				inside = false
+			}
+		default:
+			sb.WriteRune(r)
+			inside = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("%w: unterminated quote in %q", ErrInventory, line)
+	}
+
+	if inside {
+		fields = append(fields, sb.String())
+	}
+
+	return fields, nil
+}
+
+func iniVar(field string) (string, string, error) {
+	k, v, found := strings.Cut(field, "=")
+	if !found || k == "" {
+		return "", "", fmt.Errorf("%w: bad variable %q", ErrInventory, field)
+	}
+
+	return k, v, nil
+}
+
+// parseAnsibleINI reads the INI inventory. Host ranges such as r[01:10] are not supported.
+func parseAnsibleINI(data []byte) ([]HostConfig, error) {
+	var (
+		inv     = newAnsibleInventory()
+		group   = ansibleUngrouped
+		section string
+	)
+
+	inv.group(ansibleAll).children = append(inv.group(ansibleAll).children, ansibleUngrouped)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%w: bad section at line %d", ErrInventory, n)
+			}
+
+			group, section, _ = strings.Cut(line[1:len(line)-1], ":")
+			if section != "" && section != "vars" && section != "children" {
+				return nil, fmt.Errorf("%w: unknown section %s at line %d", ErrInventory, section, n)
+			}
+
+			inv.group(group)
+			if group != ansibleAll && !hasTag(inv.group(ansibleAll).children, group) {
+				inv.group(ansibleAll).children = append(inv.group(ansibleAll).children, group)
+			}
+
+			continue
+		}
+
+		fields, err := iniFields(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch section {
+		case "vars":
+			k, v, err := iniVar(strings.Join(fields, " "))
+			if err != nil {
+				return nil, fmt.Errorf("%w at line %d", err, n)
+			}
+
+			inv.group(group).vars[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		case "children":
+			g := inv.group(group)
+			g.children = append(g.children, fields[0])
+			inv.group(fields[0])
+		default:
+			vars := make(map[string]interface{})
+			for _, field := range fields[1:] {
+				k, v, err := iniVar(field)
+				if err != nil {
+					return nil, fmt.Errorf("%w at line %d", err, n)
+				}
+
+				vars[k] = v
+			}
+
+			inv.addHost(group, fields[0], vars)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInventory, err)
+	}
+
+	return inv.hostConfigs()
+}
+
+func (inv *ansibleInventory) addYAMLGroup(name string, g *ansibleYAMLGroup) {
+	group := inv.group(name)
+
+	if g == nil {
+		return
+	}
+
+	for k, v := range g.Vars {
+		group.vars[k] = v
+	}
+
+	hosts := make([]string, 0, len(g.Hosts))
+	for h := range g.Hosts {
+		hosts = append(hosts, h)
+	}
+
+	sort.Strings(hosts)
+
+	for _, h := range hosts {
+		inv.addHost(name, h, g.Hosts[h])
+	}
+
+	children := make([]string, 0, len(g.Children))
+	for child := range g.Children {
+		children = append(children, child)
+	}
+
+	sort.Strings(children)
+
+	for _, child := range children {
+		if !hasTag(group.children, child) {
+			group.children = append(group.children, child)
+		}
+
+		inv.addYAMLGroup(child, g.Children[child])
+	}
+}
+
+// parseAnsibleYAML reads the YAML inventory. Hosts are ordered by name within a group.
+func parseAnsibleYAML(data []byte) ([]HostConfig, error) {
+	var groups map[string]*ansibleYAMLGroup
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInventory, err)
+	}
+
+	inv := newAnsibleInventory()
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		inv.addYAMLGroup(name, groups[name])
+
+		if name != ansibleAll && !hasTag(inv.group(ansibleAll).children, name) {
+			inv.group(ansibleAll).children = append(inv.group(ansibleAll).children, name)
+		}
+	}
+
+	return inv.hostConfigs()
+}
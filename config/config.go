@@ -2,17 +2,22 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/jgivc/console/backup"
+	"github.com/jgivc/console/extract"
 	"github.com/jgivc/console/host"
 	"github.com/jgivc/console/transport"
 	"github.com/jgivc/console/util"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	authPromptPattern         = `(?i)((user|pass)\w+:|[\w\-]+[>#])`
 	promptPattern             = `[\w\-]+#`
+	unprivilegedPromptPattern = `[\w\-]+>`
 	authTimeout               = 5 * time.Second
 	execTimeout               = 5 * time.Second
 	usernamePromptContains    = "username:"
@@ -20,9 +25,14 @@ const (
 	promptSuffix              = "#"
 	enableSuffix              = ">"
 	enableCommand             = "en"
+	disableCommand            = "disable"
 	promptMatchLengt          = 20
 	transportReadTimeout      = time.Second
 	transportReaderBufferSize = 1024
+	recordFormat              = transport.RecordFormatCast
+	reconnectAttempts         = 3
+	reconnectBackoff          = time.Second
+	reconnectMaxBackoff       = 30 * time.Second
 )
 
 var (
@@ -32,27 +42,53 @@ var (
 
 type (
 	Config struct {
-		DefaultConfig   ConsoleConfig `yaml:"default_config"`
-		Account         host.Account  `yaml:"default_account"`
-		InitialCommands []string      `yaml:"initial_commands"`
-		Commands        []string      `yaml:"commands"`
-		ExitCommand     string        `yaml:"exit_command"`
-		Hosts           []HostConfig  `yaml:"hosts"`
+		DefaultConfig   ConsoleConfig           `yaml:"default_config"`
+		Account         host.Account            `yaml:"default_account"`
+		InitialCommands []string                `yaml:"initial_commands"`
+		Commands        []string                `yaml:"commands"`
+		ExitCommand     string                  `yaml:"exit_command"`
+		Hosts           []HostConfig            `yaml:"hosts"`
+		Backup          BackupConfig            `yaml:"backup"`
+		Diff            DiffConfig              `yaml:"diff"`
+		Extract         ExtractConfig           `yaml:"extract"`
+		Vars            map[string]interface{}  `yaml:"vars"` // Variables of command templates, see HostConfig.Vars
+		Groups          map[string]*GroupConfig `yaml:"groups"`
+		Inventory       []string                `yaml:"inventory"` // Files to load more hosts from, see LoadInventory
+		Dynamic         []DynamicInventory      `yaml:"dynamic_inventory"`
+		defaultConfig   *yaml.Node
+	}
+
+	// ExtractConfig maps commands to the extraction of records from their output.
+	ExtractConfig map[string]extract.Spec
+
+	DiffConfig struct {
+		IgnorePatterns []string `yaml:"ignore_patterns"` // Lines ignored by -diff
+	}
+
+	BackupConfig struct {
+		VolatilePatterns []string `yaml:"volatile_patterns"` // Lines to strip from backups, see backup.DefaultVolatilePatterns
 	}
 
 	HostConfig struct {
-		URI             string        `yaml:"uri"`
-		InitialCommands []string      `yaml:"initial_commands"`
-		Commands        []string      `yaml:"commands"`
-		ExitCommand     string        `yaml:"exit_command"`
-		Host            host.Host     `yaml:"-"`
-		DummyConfig     string        `yaml:"-"`
-		ConsoleConfig   ConsoleConfig `yaml:"console_config"`
+		URI             string                 `yaml:"uri"`
+		Account         *host.Account          `yaml:"account"` // Set fields replace the ones of the default or group account
+		InitialCommands []string               `yaml:"initial_commands"`
+		Commands        []string               `yaml:"commands"`
+		ExitCommand     string                 `yaml:"exit_command"`
+		Platform        string                 `yaml:"platform"`
+		Vars            map[string]interface{} `yaml:"vars"`   // Commands are templates, e.g. hostname {{.vars.name}}
+		Groups          []string               `yaml:"groups"` // All groups of the host in precedence order after Load
+		Tags            []string               `yaml:"tags"`
+		Host            host.Host              `yaml:"-"`
+		DummyConfig     string                 `yaml:"-"`
+		ConsoleConfig   ConsoleConfig          `yaml:"console_config"`
+		consoleConfig   *yaml.Node
 	}
 
 	ConsoleConfig struct {
 		AuthPromptPattern         string        `yaml:"auth_prompt_pattern"`
 		PromptPattern             string        `yaml:"prompt_pattern"`
+		UnprivilegedPromptPattern string        `yaml:"unprivileged_prompt_pattern"`
 		AuthTimeout               time.Duration `yaml:"auth_timeout"`
 		ExecTimeout               time.Duration `yaml:"exec_timeout"`
 		UsernamePromptContains    string        `yaml:"username_prompt_contains"`
@@ -60,39 +96,62 @@ type (
 		PromptSuffix              string        `yaml:"prompt_suffix"`
 		EnableSuffix              string        `yaml:"enable_suffix"`
 		EnableCommand             string        `yaml:"enable_command"`
+		DisableCommand            string        `yaml:"disable_command"`
 		PromptMatchLengt          int           `yaml:"prompt_match_lengt"`
 		TransportReadTimeout      time.Duration `yaml:"transport_read_timeout"`
 		TransportReaderBufferSize int           `yaml:"transport_reader_buffer_size"`
+		ReconnectAttempts         int           `yaml:"reconnect_attempts"`
+		ReconnectBackoff          time.Duration `yaml:"reconnect_backoff"`
+		ReconnectMaxBackoff       time.Duration `yaml:"reconnect_max_backoff"`
+		KeepAliveInterval         time.Duration `yaml:"keepalive_interval"`
+		KeepAliveCommand          string        `yaml:"keepalive_command"`
+		RecordDir                 string        `yaml:"record_dir"`
+		RecordFormat              string        `yaml:"record_format"`
+		CaptureDir                string        `yaml:"capture_dir"`
 		DummyTransportFileName    string        `yaml:"-"`
 	}
 )
 
-func (c *HostConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+// UnmarshalYAML keeps default_config to apply it to the hosts.
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	type config Config
+	if err := value.Decode((*config)(c)); err != nil {
+		return err
+	}
+
+	c.defaultConfig = mappingValue(value, "default_config")
+
+	return nil
+}
+
+func (c *HostConfig) UnmarshalYAML(value *yaml.Node) error {
 	*c = HostConfig{
 		ConsoleConfig: *DefaultConsoleConfig(),
 	}
 
-	var obj interface{}
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&c.URI)
+	}
 
-	if err := unmarshal(&obj); err != nil {
+	type hc HostConfig
+	if err := value.Decode((*hc)(c)); err != nil {
 		return err
 	}
 
-	switch v := obj.(type) {
-	case string:
-		c.URI = v
-	default:
-		type hc HostConfig
-		return unmarshal((*hc)(c))
-	}
+	c.consoleConfig = mappingValue(value, "console_config")
 
 	return nil
 }
 
 type FromFlags struct {
-	Commands    []string
-	Account     *host.Account
-	DummyConfig string
+	Commands     []string
+	Account      *host.Account
+	DummyConfig  string
+	RecordDir    string
+	RecordFormat string
+	CaptureDir   string
+	Inventory    []string
+	Filter       func(hc *HostConfig) bool // Hosts not matching are dropped before the secrets are resolved
 }
 
 func Load(fileName string, flags *FromFlags) (*Config, error) {
@@ -112,6 +171,10 @@ func Load(fileName string, flags *FromFlags) (*Config, error) {
 		cfg.ExitCommand = defaultExitCommand
 	}
 
+	if cfg.Backup.VolatilePatterns == nil {
+		cfg.Backup.VolatilePatterns = backup.DefaultVolatilePatterns
+	}
+
 	if flags.Commands != nil {
 		cfg.Commands = flags.Commands
 	}
@@ -120,19 +183,99 @@ func Load(fileName string, flags *FromFlags) (*Config, error) {
 		cfg.Account = *flags.Account
 	}
 
-	if cfg.Account.Password == "" {
-		return nil, fmt.Errorf("no account defined")
+	// Inventory files of the config are relative to it.
+	inventory := make([]string, 0, len(cfg.Inventory)+len(flags.Inventory))
+	for _, name := range cfg.Inventory {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(fileName), name)
+		}
+
+		inventory = append(inventory, name)
+	}
+
+	for _, name := range append(inventory, flags.Inventory...) {
+		hosts, err := LoadInventory(name)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Hosts = append(cfg.Hosts, hosts...)
 	}
 
-	factory := util.NewHostFactory(cfg.Account)
+	for i := range cfg.Dynamic {
+		hosts, err := cfg.Dynamic[i].Load(filepath.Dir(fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Hosts = append(cfg.Hosts, hosts...)
+	}
+
+	var scenarios *DummyScenarios
+	if flags.DummyConfig != "" {
+		var err error
+		if scenarios, err = LoadDummyScenarios(flags.DummyConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	resolver := newSecrets(filepath.Dir(fileName))
+	selected := make([]HostConfig, 0, len(cfg.Hosts))
 
 	for i := range cfg.Hosts {
-		h, err := factory.GetHost(cfg.Hosts[i].URI)
+		groups, names, err := cfg.hostGroups(&cfg.Hosts[i])
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Hosts[i].Groups = names
+
+		// The account asked with flags takes precedence over the groups and the host.
+		account := cfg.Account
+		if flags.Account == nil {
+			for _, g := range groups {
+				account = mergeAccount(account, g.Account)
+			}
+
+			account = mergeAccount(account, cfg.Hosts[i].Account)
+		}
+
+		if err = checkURI(cfg.Hosts[i].URI); err != nil {
+			return nil, err
+		}
+
+		h, err := util.NewHostFactory(account).GetHost(cfg.Hosts[i].URI)
 		if err != nil {
 			return nil, fmt.Errorf("cannot convert uri to host: %w", err)
 		}
+
 		cfg.Hosts[i].Host = *h
 
+		// The address, port, groups and tags are known, the rest is not needed for the dropped hosts.
+		if flags.Filter != nil && !flags.Filter(&cfg.Hosts[i]) {
+			continue
+		}
+
+		// Secret references are resolved in the config accounts only, not in the asked one.
+		// The uri account has no references, see checkURI.
+		if flags.Account == nil {
+			if h.Account, err = resolver.resolveAccount(h.Account); err != nil {
+				return nil, fmt.Errorf("host %s: %w", cfg.Hosts[i].URI, err)
+			}
+		}
+
+		if h.Password == "" {
+			return nil, fmt.Errorf("no account defined for host: %s", h.Host)
+		}
+
+		cfg.Hosts[i].Host = *h
+
+		if cfg.Hosts[i].ConsoleConfig, err = cfg.consoleConfig(&cfg.Hosts[i], groups); err != nil {
+			return nil, err
+		}
+
+		cfg.Hosts[i].inherit(groups)
+
 		if cfg.Hosts[i].InitialCommands == nil {
 			cfg.Hosts[i].InitialCommands = cfg.InitialCommands
 		}
@@ -149,19 +292,53 @@ func Load(fileName string, flags *FromFlags) (*Config, error) {
 			return nil, fmt.Errorf("commands cannot be empty for host: %s", cfg.Hosts[i].Host.Host)
 		}
 
-		if flags.DummyConfig != "" {
-			cfg.Hosts[i].ConsoleConfig.DummyTransportFileName = flags.DummyConfig
+		if err = cfg.Hosts[i].renderCommands(cfg.Vars); err != nil {
+			return nil, err
+		}
+
+		if flags.RecordDir != "" {
+			cfg.Hosts[i].ConsoleConfig.RecordDir = flags.RecordDir
+		}
+
+		if flags.RecordFormat != "" {
+			cfg.Hosts[i].ConsoleConfig.RecordFormat = flags.RecordFormat
+		}
+
+		if flags.CaptureDir != "" {
+			cfg.Hosts[i].ConsoleConfig.CaptureDir = flags.CaptureDir
+		}
+
+		if scenarios != nil {
+			fileName, err := scenarios.Lookup(&cfg.Hosts[i].Host)
+			if err != nil {
+				return nil, err
+			}
+
+			cfg.Hosts[i].ConsoleConfig.DummyTransportFileName = fileName
 			cfg.Hosts[i].Host.TransportType = transport.TransportDummy
 		}
+
+		selected = append(selected, cfg.Hosts[i])
 	}
 
+	cfg.Hosts = selected
+
 	return &cfg, nil
 }
 
+func valueOr(value, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
 func DefaultConsoleConfig() *ConsoleConfig {
 	return &ConsoleConfig{
 		AuthPromptPattern:         authPromptPattern,
 		PromptPattern:             promptPattern,
+		UnprivilegedPromptPattern: unprivilegedPromptPattern,
 		AuthTimeout:               authTimeout,
 		ExecTimeout:               execTimeout,
 		UsernamePromptContains:    usernamePromptContains,
@@ -169,8 +346,13 @@ func DefaultConsoleConfig() *ConsoleConfig {
 		PromptSuffix:              promptSuffix,
 		EnableSuffix:              enableSuffix,
 		EnableCommand:             enableCommand,
+		DisableCommand:            disableCommand,
 		PromptMatchLengt:          promptMatchLengt,
 		TransportReadTimeout:      transportReadTimeout,
 		TransportReaderBufferSize: transportReaderBufferSize,
+		ReconnectAttempts:         reconnectAttempts,
+		ReconnectBackoff:          reconnectBackoff,
+		ReconnectMaxBackoff:       reconnectMaxBackoff,
+		RecordFormat:              recordFormat,
 	}
 }
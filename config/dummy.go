@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jgivc/console/host"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	dummyDefaultKey  = "default"
+	dummyScenarioExt = ".xml"
+)
+
+var ErrNoScenario = errors.New("no dummy transport scenario")
+
+/*
+DummyScenarios selects the dummy transport scenario for every host. The source is one of:
+
+	scenario.xml    the same scenario for every host
+	dir             dir/<host>.xml, or dir/default.xml if there is no file for the host
+	scenarios.yml   map from host address to scenario file, the "default" key is used for other hosts
+
+Relative paths in the yaml file are relative to the file itself.
+*/
+type DummyScenarios struct {
+	file  string
+	dir   string
+	hosts map[string]string
+}
+
+// Lookup returns the scenario file for the host. Hosts are matched by host:port first, then by address.
+func (s *DummyScenarios) Lookup(h *host.Host) (string, error) {
+	switch {
+	case s.file != "":
+		return s.file, nil
+	case s.dir != "":
+		for _, name := range []string{h.GetHostPort(), h.Host, dummyDefaultKey} {
+			fileName := path.Join(s.dir, name+dummyScenarioExt)
+			if _, err := os.Stat(fileName); err == nil {
+				return fileName, nil
+			}
+		}
+	default:
+		for _, name := range []string{h.GetHostPort(), h.Host, dummyDefaultKey} {
+			if fileName, exists := s.hosts[name]; exists {
+				return fileName, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w for host: %s", ErrNoScenario, h.Host)
+}
+
+func LoadDummyScenarios(source string) (*DummyScenarios, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open dummy transport config: %w", err)
+	}
+
+	if info.IsDir() {
+		return &DummyScenarios{dir: source}, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".yml", ".yaml":
+	default:
+		return &DummyScenarios{file: source}, nil
+	}
+
+	b, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read dummy transport config: %w", err)
+	}
+
+	var hosts map[string]string
+	if err2 := yaml.Unmarshal(b, &hosts); err2 != nil {
+		return nil, fmt.Errorf("cannot parse dummy transport config: %w", err2)
+	}
+
+	dir := path.Dir(source)
+	for name, fileName := range hosts {
+		if !path.IsAbs(fileName) {
+			hosts[name] = path.Join(dir, fileName)
+		}
+	}
+
+	return &DummyScenarios{hosts: hosts}, nil
+}
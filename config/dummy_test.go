@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jgivc/console/transport"
+	"github.com/stretchr/testify/suite"
+)
+
+const testConfig = `default_account:
+  username: admin
+  password: password
+commands:
+  - sh ver
+hosts:
+  - 10.0.0.1
+  - 10.0.0.2
+  - 10.0.0.3:2222
+`
+
+type DummyScenariosTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *DummyScenariosTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *DummyScenariosTestSuite) write(name, data string) string {
+	fileName := path.Join(suite.dir, name)
+	suite.Require().NoError(os.MkdirAll(path.Dir(fileName), 0755))
+	suite.Require().NoError(os.WriteFile(fileName, []byte(data), 0600))
+
+	return fileName
+}
+
+func (suite *DummyScenariosTestSuite) load(source string) []string {
+	cfg, err := Load(suite.write("config.yml", testConfig), &FromFlags{DummyConfig: source})
+	suite.Require().NoError(err)
+
+	var files []string
+	for _, h := range cfg.Hosts {
+		suite.Equal(transport.TransportDummy, h.Host.TransportType)
+		files = append(files, h.ConsoleConfig.DummyTransportFileName)
+	}
+
+	return files
+}
+
+func (suite *DummyScenariosTestSuite) TestFile() {
+	fileName := suite.write("sw.xml", "<scenario/>")
+	suite.Equal([]string{fileName, fileName, fileName}, suite.load(fileName))
+}
+
+func (suite *DummyScenariosTestSuite) TestDir() {
+	sw1 := suite.write("scenarios/10.0.0.1.xml", "<scenario/>")
+	sw3 := suite.write("scenarios/10.0.0.3:2222.xml", "<scenario/>")
+	def := suite.write("scenarios/default.xml", "<scenario/>")
+
+	suite.Equal([]string{sw1, def, sw3}, suite.load(path.Join(suite.dir, "scenarios")))
+}
+
+func (suite *DummyScenariosTestSuite) TestYAML() {
+	source := suite.write("scenarios.yml", "10.0.0.1: sw1.xml\n10.0.0.3: /abs/sw3.xml\ndefault: default.xml\n")
+
+	suite.Equal([]string{
+		path.Join(suite.dir, "sw1.xml"),
+		path.Join(suite.dir, "default.xml"),
+		"/abs/sw3.xml",
+	}, suite.load(source))
+}
+
+func (suite *DummyScenariosTestSuite) TestNoScenario() {
+	source := suite.write("scenarios.yaml", "10.0.0.1: sw1.xml\n")
+
+	_, err := Load(suite.write("config.yml", testConfig), &FromFlags{DummyConfig: source})
+	suite.ErrorIs(err, ErrNoScenario)
+	suite.ErrorContains(err, "10.0.0.2")
+}
+
+func TestDummyScenariosTestSuite(t *testing.T) {
+	suite.Run(t, new(DummyScenariosTestSuite))
+}
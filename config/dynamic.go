@@ -0,0 +1,223 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const dynamicInventoryTimeout = 30 * time.Second
+
+/*
+DynamicInventory gets the hosts at runtime from the stdout of the command or from the url.
+The output is the JSON list of hosts, see LoadInventory:
+
+	[
+	  {"address": "10.0.0.1", "platform": "ios", "tags": ["core"], "vars": {"site": "dc1"}},
+	  {"uri": "ssh://10.0.0.2:2222", "groups": ["core"], "commands": ["sh ver"]}
+	]
+
+With cache_ttl the output is saved to cache_file, by default in the user cache dir,
+and used instead of the source while it is newer than the ttl. The output is saved as is,
+so the accounts should have secret references instead of plaintext passwords.
+*/
+type DynamicInventory struct {
+	Command   []string          `yaml:"command"` // Run in the config dir without shell
+	URL       string            `yaml:"url"`
+	Headers   map[string]string `yaml:"headers"` // Headers of the url request, e.g. Authorization
+	Timeout   time.Duration     `yaml:"timeout"`
+	CacheTTL  time.Duration     `yaml:"cache_ttl"`
+	CacheFile string            `yaml:"cache_file"`
+}
+
+func (d *DynamicInventory) source() string {
+	if d.URL != "" {
+		return d.URL
+	}
+
+	return strings.Join(d.Command, " ")
+}
+
+// cacheKey identifies the output: the same command in another dir or the url with other headers differs.
+func (d *DynamicInventory) cacheKey(dir string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintln(h, d.source())
+
+	if d.URL == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", fmt.Errorf("cannot get inventory dir: %w", err)
+		}
+
+		fmt.Fprintln(h, abs)
+	}
+
+	keys := make([]string, 0, len(d.Headers))
+	for k := range d.Headers {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s: %s\n", k, d.Headers[k])
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func (d *DynamicInventory) cacheFile(dir string) (string, error) {
+	if d.CacheFile != "" {
+		return d.CacheFile, nil
+	}
+
+	key, err := d.cacheKey(dir)
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot get cache dir: %w", err)
+	}
+
+	return filepath.Join(cacheDir, "console", "inventory-"+key+".json"), nil
+}
+
+// readCache returns the cached output if it is not expired, or nil.
+func (d *DynamicInventory) readCache(fileName string) []byte {
+	fi, err := os.Stat(fileName)
+	if err != nil || time.Since(fi.ModTime()) > d.CacheTTL {
+		return nil
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+func (d *DynamicInventory) writeCache(fileName string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return fmt.Errorf("cannot create inventory cache dir: %w", err)
+	}
+
+	if err := os.WriteFile(fileName, data, 0600); err != nil {
+		return fmt.Errorf("cannot write inventory cache: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DynamicInventory) run(ctx context.Context, dir string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, d.Command[0], d.Command[1:]...)
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+
+		// Stderr is not included, it can reveal tokens as the secret helpers do.
+		return nil, fmt.Errorf("inventory command failed: %w", err)
+	}
+
+	return out, nil
+}
+
+func (d *DynamicInventory) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create inventory request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	for k, v := range d.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch inventory: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch inventory: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read inventory: %w", err)
+	}
+
+	return data, nil
+}
+
+// Load returns the hosts from the cache or the source. The command is run in dir.
+func (d *DynamicInventory) Load(dir string) ([]HostConfig, error) {
+	if (d.URL == "") == (len(d.Command) == 0) {
+		return nil, fmt.Errorf("%w: dynamic inventory needs either command or url", ErrInventory)
+	}
+
+	var (
+		cacheFile string
+		data      []byte
+		cached    bool
+		err       error
+	)
+
+	if d.CacheTTL > 0 {
+		if cacheFile, err = d.cacheFile(dir); err != nil {
+			return nil, err
+		}
+
+		data = d.readCache(cacheFile)
+		cached = data != nil
+	}
+
+	if data == nil {
+		timeout := d.Timeout
+		if timeout <= 0 {
+			timeout = dynamicInventoryTimeout
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if d.URL != "" {
+			data, err = d.fetch(ctx)
+		} else {
+			data, err = d.run(ctx, dir)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hosts, err := parseJSONInventory(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load inventory from %s: %w", d.source(), err)
+	}
+
+	// Only the valid output is cached.
+	if cacheFile != "" && !cached {
+		if err = d.writeCache(cacheFile, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return hosts, nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DynamicTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *DynamicTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *DynamicTestSuite) TestCommand() {
+	script := "#!/bin/sh\necho '[{\"address\": \"10.0.0.1\", \"tags\": [\"'$1'\"]}]'\n"
+	suite.Require().NoError(os.WriteFile(path.Join(suite.dir, "cmdb.sh"), []byte(script), 0700))
+
+	d := DynamicInventory{Command: []string{"./cmdb.sh", "core"}}
+	hosts, err := d.Load(suite.dir)
+	suite.Require().NoError(err)
+	suite.Require().Len(hosts, 1)
+	suite.Equal("10.0.0.1", hosts[0].URI)
+	suite.Equal([]string{"core"}, hosts[0].Tags)
+
+	d = DynamicInventory{Command: []string{"sh", "-c", "echo token-secret >&2; exit 1"}}
+	_, err = d.Load(suite.dir)
+	suite.ErrorContains(err, "inventory command failed: exit status 1")
+	suite.NotContains(err.Error(), "token-secret")
+
+	d = DynamicInventory{Command: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond}
+	_, err = d.Load(suite.dir)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (suite *DynamicTestSuite) TestURL() {
+	var requests atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		n := requests.Add(1)
+		fmt.Fprintf(w, `[{"address": "10.0.0.%d"}]`, n)
+	}))
+	defer srv.Close()
+
+	d := DynamicInventory{URL: srv.URL}
+	_, err := d.Load(suite.dir)
+	suite.ErrorContains(err, "401")
+
+	cacheFile := path.Join(suite.dir, "cache", "inventory.json")
+	d = DynamicInventory{
+		URL:       srv.URL,
+		Headers:   map[string]string{"Authorization": "Token secret"},
+		CacheTTL:  time.Minute,
+		CacheFile: cacheFile,
+	}
+
+	hosts, err := d.Load(suite.dir)
+	suite.Require().NoError(err)
+	suite.Equal("10.0.0.1", hosts[0].URI)
+
+	// From the cache.
+	hosts, err = d.Load(suite.dir)
+	suite.Require().NoError(err)
+	suite.Equal("10.0.0.1", hosts[0].URI)
+	suite.Equal(int32(1), requests.Load())
+
+	// Expired.
+	old := time.Now().Add(-2 * time.Minute)
+	suite.Require().NoError(os.Chtimes(cacheFile, old, old))
+
+	hosts, err = d.Load(suite.dir)
+	suite.Require().NoError(err)
+	suite.Equal("10.0.0.2", hosts[0].URI)
+}
+
+func (suite *DynamicTestSuite) TestCacheKey() {
+	key := func(d DynamicInventory, dir string) string {
+		k, err := d.cacheKey(dir)
+		suite.Require().NoError(err)
+
+		return k
+	}
+
+	cmd := DynamicInventory{Command: []string{"./cmdb.sh"}}
+	suite.NotEqual(key(cmd, "/etc/a"), key(cmd, "/etc/b"))
+	suite.Equal(key(cmd, "/etc/a"), key(cmd, "/etc/a/"))
+
+	url := DynamicInventory{URL: "https://cmdb"}
+	suite.Equal(key(url, "/etc/a"), key(url, "/etc/b"))
+
+	auth := DynamicInventory{URL: "https://cmdb", Headers: map[string]string{"Authorization": "Token a", "X-Site": "dc1"}}
+	suite.NotEqual(key(url, "/etc/a"), key(auth, "/etc/a"))
+	suite.Equal(key(auth, "/etc/a"), key(auth, "/etc/a"))
+
+	auth.Headers = map[string]string{"Authorization": "Token b", "X-Site": "dc1"}
+	suite.NotEqual(key(auth, "/etc/a"), key(DynamicInventory{URL: "https://cmdb",
+		Headers: map[string]string{"Authorization": "Token a", "X-Site": "dc1"}}, "/etc/a"))
+}
+
+func (suite *DynamicTestSuite) TestLoad() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"address": "10.0.0.1", "vars": {"site": "dc1"}}]`)
+	}))
+	defer srv.Close()
+
+	fileName := path.Join(suite.dir, "config.yml")
+	suite.Require().NoError(os.WriteFile(fileName, []byte(fmt.Sprintf(`default_account:
+  username: admin
+  password: password
+commands:
+  - sh ver
+dynamic_inventory:
+  - url: %s
+    timeout: 5s
+`, srv.URL)), 0600))
+
+	cfg, err := Load(fileName, &FromFlags{})
+	suite.Require().NoError(err)
+	suite.Require().Len(cfg.Hosts, 1)
+	suite.Equal("admin", cfg.Hosts[0].Host.Username)
+	suite.Equal([]string{"sh ver"}, cfg.Hosts[0].Commands)
+
+	_, err = (&DynamicInventory{}).Load(suite.dir)
+	suite.ErrorIs(err, ErrInventory)
+}
+
+func TestDynamicTestSuite(t *testing.T) {
+	suite.Run(t, new(DynamicTestSuite))
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jgivc/console/host"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+GroupConfig is the shared config of the hosts in the group. A host is in the group
+if the group is in the host groups or the host has any of the group tags.
+
+Settings are applied in order, the later ones take precedence:
+
+	default_config, default_account, global commands and vars
+	groups selected by tags, in the order of group names
+	groups listed in the host groups, in the listed order
+	host settings
+
+console_config and vars are merged key by key, account field by field, the other settings are replaced.
+*/
+type GroupConfig struct {
+	Account         *host.Account          `yaml:"account"`
+	ConsoleConfig   yaml.Node              `yaml:"console_config"`
+	InitialCommands []string               `yaml:"initial_commands"`
+	Commands        []string               `yaml:"commands"`
+	ExitCommand     string                 `yaml:"exit_command"`
+	Platform        string                 `yaml:"platform"`
+	Vars            map[string]interface{} `yaml:"vars"`
+	Tags            []string               `yaml:"tags"` // Hosts with any of the tags are in the group
+}
+
+// mappingValue returns the value of the key in the yaml mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeAccount returns the account with the set fields of a.
+func mergeAccount(account host.Account, a *host.Account) host.Account {
+	if a != nil {
+		account.Username = valueOr(a.Username, account.Username)
+		account.Password = valueOr(a.Password, account.Password)
+		account.EnablePassword = valueOr(a.EnablePassword, account.EnablePassword)
+	}
+
+	return account
+}
+
+// hostGroups returns the groups of the host from the lowest precedence and their names.
+func (c *Config) hostGroups(hc *HostConfig) ([]*GroupConfig, []string, error) {
+	var (
+		groups []*GroupConfig
+		names  []string
+	)
+
+	for name, g := range c.Groups {
+		if hasTag(hc.Groups, name) {
+			continue
+		}
+
+		for _, tag := range g.Tags {
+			if hasTag(hc.Tags, tag) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(names)
+	names = append(names, hc.Groups...)
+
+	for _, name := range names {
+		g, exists := c.Groups[name]
+		if !exists {
+			return nil, nil, fmt.Errorf("unknown group %s for host: %s", name, hc.URI)
+		}
+
+		groups = append(groups, g)
+	}
+
+	return groups, names, nil
+}
+
+// consoleConfig returns the console config of the host built from all levels.
+func (c *Config) consoleConfig(hc *HostConfig, groups []*GroupConfig) (ConsoleConfig, error) {
+	cc := *DefaultConsoleConfig()
+
+	nodes := []*yaml.Node{c.defaultConfig}
+	for _, g := range groups {
+		nodes = append(nodes, &g.ConsoleConfig)
+	}
+
+	nodes = append(nodes, hc.consoleConfig)
+
+	for _, node := range nodes {
+		if node == nil || node.Kind == 0 {
+			continue
+		}
+
+		if err := node.Decode(&cc); err != nil {
+			return cc, fmt.Errorf("cannot decode console_config for host %s: %w", hc.URI, err)
+		}
+	}
+
+	return cc, nil
+}
+
+// inherit sets the host settings that are not set from the groups.
+func (c *HostConfig) inherit(groups []*GroupConfig) {
+	var g GroupConfig
+
+	vars := make(map[string]interface{})
+
+	for _, group := range groups {
+		if group.InitialCommands != nil {
+			g.InitialCommands = group.InitialCommands
+		}
+
+		if group.Commands != nil {
+			g.Commands = group.Commands
+		}
+
+		if group.ExitCommand != "" {
+			g.ExitCommand = group.ExitCommand
+		}
+
+		if group.Platform != "" {
+			g.Platform = group.Platform
+		}
+
+		for k, v := range group.Vars {
+			vars[k] = v
+		}
+	}
+
+	if c.InitialCommands == nil {
+		c.InitialCommands = g.InitialCommands
+	}
+
+	if c.Commands == nil {
+		c.Commands = g.Commands
+	}
+
+	if c.ExitCommand == "" {
+		c.ExitCommand = g.ExitCommand
+	}
+
+	if c.Platform == "" {
+		c.Platform = g.Platform
+	}
+
+	for k, v := range c.Vars {
+		vars[k] = v
+	}
+
+	c.Vars = vars
+}
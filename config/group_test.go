@@ -0,0 +1,174 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/jgivc/console/host"
+	"github.com/stretchr/testify/suite"
+)
+
+const testGroupConfig = `default_config:
+  exec_timeout: 10s
+default_account:
+  username: admin
+  password: password
+commands:
+  - sh ver
+vars:
+  ntp: 10.0.0.100
+groups:
+  core:
+    account:
+      username: core
+      password: core-password
+    console_config:
+      exec_timeout: 30s
+      keepalive_interval: 1m
+    commands:
+      - sh run
+    vars:
+      role: core
+  nxos:
+    platform: nxos
+    console_config:
+      prompt_pattern: '[\w\-]+(\(\w+\))?#'
+    vars:
+      role: nxos
+  dc1:
+    tags: [dc1]
+    vars:
+      site: dc1
+      role: dc1
+    exit_command: exit
+hosts:
+  - 10.0.0.1
+  - uri: 10.0.0.2
+    groups: [core, nxos]
+    tags: [dc1]
+  - uri: user:secret@10.0.0.3
+    groups: [nxos, core]
+    console_config:
+      exec_timeout: 1m
+    vars:
+      role: edge
+    commands:
+      - sh clock
+`
+
+type GroupTestSuite struct {
+	suite.Suite
+}
+
+func (suite *GroupTestSuite) load(data string, flags *FromFlags) (*Config, error) {
+	fileName := path.Join(suite.T().TempDir(), "config.yml")
+	suite.Require().NoError(os.WriteFile(fileName, []byte(data), 0600))
+
+	return Load(fileName, flags)
+}
+
+func (suite *GroupTestSuite) TestInheritance() {
+	cfg, err := suite.load(testGroupConfig, &FromFlags{})
+	suite.Require().NoError(err)
+
+	// Defaults only.
+	h := cfg.Hosts[0]
+	suite.Equal("admin", h.Host.Username)
+	suite.Equal(10*time.Second, h.ConsoleConfig.ExecTimeout)
+	suite.Equal(promptPattern, h.ConsoleConfig.PromptPattern)
+	suite.Equal([]string{"sh ver"}, h.Commands)
+	suite.Equal(defaultExitCommand, h.ExitCommand)
+	suite.Empty(h.Vars)
+
+	// dc1 by tag, then core and nxos as listed.
+	h = cfg.Hosts[1]
+	suite.Equal([]string{"dc1", "core", "nxos"}, h.Groups)
+	suite.Equal(host.Account{Username: "core", Password: "core-password"}, h.Host.Account)
+	suite.Equal(30*time.Second, h.ConsoleConfig.ExecTimeout)
+	suite.Equal(time.Minute, h.ConsoleConfig.KeepAliveInterval)
+	suite.Equal(`[\w\-]+(\(\w+\))?#`, h.ConsoleConfig.PromptPattern)
+	suite.Equal(authTimeout, h.ConsoleConfig.AuthTimeout)
+	suite.Equal([]string{"sh run"}, h.Commands)
+	suite.Equal("exit", h.ExitCommand)
+	suite.Equal("nxos", h.Platform)
+	suite.Equal(map[string]interface{}{"site": "dc1", "role": "nxos"}, h.Vars)
+
+	// Host settings take precedence.
+	h = cfg.Hosts[2]
+	suite.Equal(host.Account{Username: "user", Password: "secret"}, h.Host.Account)
+	suite.Equal(time.Minute, h.ConsoleConfig.ExecTimeout)
+	suite.Equal([]string{"sh clock"}, h.Commands)
+	suite.Equal(map[string]interface{}{"role": "edge"}, h.Vars)
+}
+
+func (suite *GroupTestSuite) TestFlags() {
+	account := &host.Account{Username: "asked", Password: "asked-password"}
+
+	cfg, err := suite.load(testGroupConfig, &FromFlags{Commands: []string{"sh int"}, Account: account})
+	suite.Require().NoError(err)
+
+	suite.Equal(*account, cfg.Hosts[1].Host.Account)
+	suite.Equal([]string{"sh int"}, cfg.Hosts[1].Commands)
+}
+
+func (suite *GroupTestSuite) TestAccountMerge() {
+	cfg, err := suite.load(`default_account:
+  username: admin
+  password: password
+commands:
+  - sh ver
+groups:
+  enable:
+    account:
+      enable_password: enable-password
+  operator:
+    account:
+      username: operator
+hosts:
+  - uri: 10.0.0.1
+    groups: [enable]
+  - uri: 10.0.0.2
+    groups: [enable, operator]
+    account:
+      password: host-password
+`, &FromFlags{})
+	suite.Require().NoError(err)
+
+	suite.Equal(host.Account{Username: "admin", Password: "password", EnablePassword: "enable-password"},
+		cfg.Hosts[0].Host.Account)
+	suite.Equal(host.Account{Username: "operator", Password: "host-password", EnablePassword: "enable-password"},
+		cfg.Hosts[1].Host.Account)
+}
+
+func (suite *GroupTestSuite) TestUnknownGroup() {
+	_, err := suite.load(`default_account:
+  password: password
+commands:
+  - sh ver
+hosts:
+  - uri: 10.0.0.1
+    groups: [missing]
+`, &FromFlags{})
+	suite.ErrorContains(err, "unknown group missing")
+}
+
+func (suite *GroupTestSuite) TestNoAccount() {
+	_, err := suite.load(`commands:
+  - sh ver
+groups:
+  core:
+    account:
+      password: password
+hosts:
+  - uri: 10.0.0.1
+    groups: [core]
+  - 10.0.0.2
+`, &FromFlags{})
+	suite.ErrorContains(err, "no account defined for host: 10.0.0.2")
+}
+
+func TestGroupTestSuite(t *testing.T) {
+	suite.Run(t, new(GroupTestSuite))
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInventory = errors.New("bad inventory")
+
+/*
+LoadInventory reads the hosts from the inventory file, the format is selected by the extension:
+
+	.csv         columns address or uri, platform, tags, groups, vars, other columns are vars
+	.json        list of hosts with the keys of the config hosts, address can be used instead of uri
+	.yml, .yaml  Ansible YAML inventory
+	other        Ansible INI inventory
+
+The hosts get the defaults, groups and vars of the config like the hosts listed in it.
+*/
+func LoadInventory(fileName string) ([]HostConfig, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read inventory: %w", err)
+	}
+
+	var hosts []HostConfig
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		hosts, err = parseCSVInventory(data)
+	case ".json":
+		hosts, err = parseJSONInventory(data)
+	case ".yml", ".yaml":
+		hosts, err = parseAnsibleYAML(data)
+	default:
+		hosts, err = parseAnsibleINI(data)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot load inventory %s: %w", fileName, err)
+	}
+
+	return hosts, nil
+}
+
+// splitList splits the list of tags or groups separated by ; or spaces.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == ' '
+	})
+}
+
+// parseCSVInventory reads the hosts from csv with a header. Vars are written as key=value;key=value.
+func parseCSVInventory(data []byte) ([]HostConfig, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read csv header: %v", ErrInventory, err)
+	}
+
+	var hosts []HostConfig
+
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInventory, err)
+		}
+
+		hc := HostConfig{ConsoleConfig: *DefaultConsoleConfig()}
+
+		for i, column := range header {
+			value := strings.TrimSpace(row[i])
+			if value == "" {
+				continue
+			}
+
+			switch strings.ToLower(strings.TrimSpace(column)) {
+			case "address":
+				if hc.URI == "" {
+					hc.URI = value
+				}
+			case "uri":
+				hc.URI = value
+			case "platform":
+				hc.Platform = value
+			case "tags":
+				hc.Tags = splitList(value)
+			case "groups":
+				hc.Groups = splitList(value)
+			case "vars":
+				for _, kv := range strings.Split(value, ";") {
+					k, v, found := strings.Cut(kv, "=")
+					if !found {
+						return nil, fmt.Errorf("%w: bad vars %q of host %s", ErrInventory, value, row[0])
+					}
+
+					hc.setVar(strings.TrimSpace(k), strings.TrimSpace(v))
+				}
+			default:
+				hc.setVar(strings.TrimSpace(column), value)
+			}
+		}
+
+		if hc.URI == "" {
+			return nil, fmt.Errorf("%w: no address in row %v", ErrInventory, row)
+		}
+
+		hosts = append(hosts, hc)
+	}
+
+	return hosts, nil
+}
+
+func (c *HostConfig) setVar(key string, value interface{}) {
+	if c.Vars == nil {
+		c.Vars = make(map[string]interface{})
+	}
+
+	c.Vars[key] = value
+}
+
+// parseJSONInventory reads the list of hosts. JSON is decoded as yaml to use the config keys.
+func parseJSONInventory(data []byte) ([]HostConfig, error) {
+	var list yaml.Node
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInventory, err)
+	}
+
+	if len(list.Content) == 0 {
+		return nil, nil
+	}
+
+	if list.Content[0].Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%w: list of hosts expected", ErrInventory)
+	}
+
+	hosts := make([]HostConfig, 0, len(list.Content[0].Content))
+
+	for _, node := range list.Content[0].Content {
+		var hc HostConfig
+		if err := node.Decode(&hc); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInventory, err)
+		}
+
+		if address := mappingValue(node, "address"); address != nil && hc.URI == "" {
+			hc.URI = address.Value
+		}
+
+		if hc.URI == "" {
+			return nil, fmt.Errorf("%w: no address of host at line %d", ErrInventory, node.Line)
+		}
+
+		hosts = append(hosts, hc)
+	}
+
+	return hosts, nil
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jgivc/console/host"
+	"github.com/jgivc/console/transport"
+	"github.com/stretchr/testify/suite"
+)
+
+const testINIInventory = `# Exported from the source of truth
+r0 ansible_host=10.0.0.10
+
+[core]
+r1 ansible_host=10.0.0.1 ansible_network_os=cisco.ios.ios role=core
+r2 ansible_host=10.0.0.2 ansible_port=2222 descr="core 2"
+
+[edge]
+r3 ansible_host=10.0.0.3 ansible_connection=telnet ansible_user=edge ansible_password=edge-password
+
+[dc1:children]
+core
+edge
+
+[dc1:vars]
+site=dc1
+ansible_network_os=cisco.nxos.nxos
+
+[all:vars]
+ansible_user=ansible
+`
+
+const testYAMLInventory = `all:
+  vars:
+    ansible_user: ansible
+  children:
+    dc1:
+      vars:
+        site: dc1
+        ansible_network_os: cisco.nxos.nxos
+      children:
+        core:
+          hosts:
+            r1:
+              ansible_host: 10.0.0.1
+              ansible_network_os: cisco.ios.ios
+              role: core
+            r2:
+              ansible_host: 10.0.0.2
+              ansible_port: 2222
+        edge:
+          hosts:
+            r3:
+              ansible_host: 10.0.0.3
+              ansible_connection: telnet
+              ansible_user: edge
+              ansible_password: edge-password
+  hosts:
+    r0:
+      ansible_host: 10.0.0.10
+`
+
+type InventoryTestSuite struct {
+	suite.Suite
+}
+
+func (suite *InventoryTestSuite) write(name, data string) string {
+	fileName := path.Join(suite.T().TempDir(), name)
+	suite.Require().NoError(os.WriteFile(fileName, []byte(data), 0600))
+
+	return fileName
+}
+
+func (suite *InventoryTestSuite) TestCSV() {
+	hosts, err := LoadInventory(suite.write("hosts.csv", `address,uri,platform,tags,vars,site
+10.0.0.1,,ios,core;dc1,role=core;vlan=10,dc1
+10.0.0.2,ssh://10.0.0.2,nxos,,,
+`))
+	suite.Require().NoError(err)
+	suite.Require().Len(hosts, 2)
+
+	suite.Equal("10.0.0.1", hosts[0].URI)
+	suite.Equal("ios", hosts[0].Platform)
+	suite.Equal([]string{"core", "dc1"}, hosts[0].Tags)
+	suite.Equal(map[string]interface{}{"role": "core", "vlan": "10", "site": "dc1"}, hosts[0].Vars)
+	suite.Equal("ssh://10.0.0.2", hosts[1].URI)
+	suite.Nil(hosts[1].Vars)
+
+	_, err = LoadInventory(suite.write("bad.csv", "address,vars\n10.0.0.1,role\n"))
+	suite.ErrorIs(err, ErrInventory)
+
+	_, err = LoadInventory(suite.write("bad.csv", "platform\nios\n"))
+	suite.ErrorIs(err, ErrInventory)
+}
+
+func (suite *InventoryTestSuite) TestJSON() {
+	hosts, err := LoadInventory(suite.write("hosts.json", `[
+  {"address": "10.0.0.1", "platform": "ios", "tags": ["core"], "vars": {"vlan": 10}},
+  {"uri": "ssh://10.0.0.2", "commands": ["sh clock"], "console_config": {"exec_timeout": "1m"}}
+]`))
+	suite.Require().NoError(err)
+	suite.Require().Len(hosts, 2)
+
+	suite.Equal("10.0.0.1", hosts[0].URI)
+	suite.Equal([]string{"core"}, hosts[0].Tags)
+	suite.Equal(map[string]interface{}{"vlan": 10}, hosts[0].Vars)
+	suite.Equal("ssh://10.0.0.2", hosts[1].URI)
+	suite.Equal([]string{"sh clock"}, hosts[1].Commands)
+
+	_, err = LoadInventory(suite.write("bad.json", `{"address": "10.0.0.1"}`))
+	suite.ErrorIs(err, ErrInventory)
+
+	_, err = LoadInventory(suite.write("bad.json", `[{"platform": "ios"}]`))
+	suite.ErrorIs(err, ErrInventory)
+}
+
+func (suite *InventoryTestSuite) checkAnsible(hosts []HostConfig) {
+	suite.Require().Len(hosts, 4)
+
+	byName := make(map[string]HostConfig)
+	for _, hc := range hosts {
+		byName[hc.Vars["inventory_hostname"].(string)] = hc
+	}
+
+	r0 := byName["r0"]
+	suite.Equal("ssh://10.0.0.10", r0.URI)
+	suite.Empty(r0.Tags)
+	suite.Equal(&host.Account{Username: "ansible"}, r0.Account)
+
+	r1 := byName["r1"]
+	suite.Equal("ssh://10.0.0.1", r1.URI)
+	suite.Equal("ios", r1.Platform)
+	suite.Equal([]string{"dc1", "core"}, r1.Tags)
+	suite.Equal(map[string]interface{}{"inventory_hostname": "r1", "role": "core", "site": "dc1"}, r1.Vars)
+
+	r2 := byName["r2"]
+	suite.Equal("ssh://10.0.0.2:2222", r2.URI)
+	suite.Equal("nxos", r2.Platform)
+
+	r3 := byName["r3"]
+	suite.Equal("telnet://10.0.0.3", r3.URI)
+	suite.Equal([]string{"dc1", "edge"}, r3.Tags)
+	suite.Equal(&host.Account{Username: "edge", Password: "edge-password"}, r3.Account)
+}
+
+func (suite *InventoryTestSuite) TestAnsibleINI() {
+	hosts, err := LoadInventory(suite.write("hosts", testINIInventory))
+	suite.Require().NoError(err)
+	suite.checkAnsible(hosts)
+
+	suite.Equal("r0", hosts[0].Vars["inventory_hostname"])
+	suite.Equal("core 2", hosts[2].Vars["descr"])
+
+	for _, data := range []string{
+		"[core\nr1\n",
+		"[core:hosts]\nr1\n",
+		"r1 ansible_host\n",
+		"r1 descr=\"core\n",
+		"[a:children]\nb\n[b:children]\na\n",
+	} {
+		_, err = LoadInventory(suite.write("hosts.ini", data))
+		suite.ErrorIs(err, ErrInventory, data)
+	}
+}
+
+func (suite *InventoryTestSuite) TestAnsibleYAML() {
+	hosts, err := LoadInventory(suite.write("hosts.yml", testYAMLInventory))
+	suite.Require().NoError(err)
+	suite.checkAnsible(hosts)
+
+	_, err = LoadInventory(suite.write("hosts.yml", "all: [r1]\n"))
+	suite.ErrorIs(err, ErrInventory)
+}
+
+func (suite *InventoryTestSuite) TestLoad() {
+	dir := suite.T().TempDir()
+	suite.Require().NoError(os.WriteFile(path.Join(dir, "hosts"), []byte(testINIInventory), 0600))
+
+	fileName := path.Join(dir, "config.yml")
+	suite.Require().NoError(os.WriteFile(fileName, []byte(`default_account:
+  username: admin
+  password: password
+commands:
+  - sh ver
+inventory:
+  - hosts
+groups:
+  dc1:
+    tags: [dc1]
+    commands:
+      - sh run
+hosts:
+  - 10.0.0.100
+`), 0600))
+
+	cfg, err := Load(fileName, &FromFlags{})
+	suite.Require().NoError(err)
+	suite.Require().Len(cfg.Hosts, 5)
+
+	suite.Equal("10.0.0.100", cfg.Hosts[0].Host.Host)
+
+	r0 := cfg.Hosts[1]
+	suite.Equal(host.Host{Host: "10.0.0.10", Port: 22, TransportType: transport.TransportSSH,
+		Account: host.Account{Username: "ansible", Password: "password"}}, r0.Host)
+	suite.Equal([]string{"sh ver"}, r0.Commands)
+
+	r3 := cfg.Hosts[4]
+	suite.Equal(host.Account{Username: "edge", Password: "edge-password"}, r3.Host.Account)
+	suite.Equal(23, r3.Host.Port)
+	suite.Equal([]string{"dc1"}, r3.Groups)
+	suite.Equal([]string{"sh run"}, r3.Commands)
+
+	// The account of flags takes precedence.
+	account := host.Account{Username: "user", Password: "secret"}
+	cfg, err = Load(fileName, &FromFlags{Account: &account})
+	suite.Require().NoError(err)
+	suite.Equal(account, cfg.Hosts[4].Host.Account)
+}
+
+func TestInventoryTestSuite(t *testing.T) {
+	suite.Run(t, new(InventoryTestSuite))
+}
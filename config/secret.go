@@ -0,0 +1,150 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jgivc/console/host"
+)
+
+const (
+	secretEnv     = "env:"
+	secretFile    = "file:"
+	secretCmd     = "cmd:"
+	secretTimeout = 30 * time.Second
+)
+
+var (
+	ErrSecret = errors.New("cannot resolve secret")
+
+	// uriSecretRef matches a reference in the credentials of the host uri.
+	uriSecretRef = regexp.MustCompile(`^(?:\w+://)?(?:.*:)?(?:env|file|cmd):.*@`)
+)
+
+/*
+secrets resolves the references in account fields, other values are returned as is:
+
+	env:NAME         environment variable NAME
+	file:/path       content of the file without trailing newlines
+	cmd:helper args  stdout of the command run without shell, e.g. cmd:pass show network/admin
+
+Relative file paths are resolved against dir and the command is run in it, as inventory files and
+dynamic inventory commands are. The command is split by spaces, quoted arguments are not supported.
+Every reference is resolved once. Errors contain the reference only, never the value or the helper
+stderr, which can reveal secrets.
+*/
+type secrets struct {
+	dir    string
+	values map[string]string
+}
+
+func newSecrets(dir string) *secrets {
+	return &secrets{
+		dir:    dir,
+		values: make(map[string]string),
+	}
+}
+
+func (s *secrets) resolve(ref string) (string, error) {
+	if value, exists := s.values[ref]; exists {
+		return value, nil
+	}
+
+	var (
+		value string
+		err   error
+	)
+
+	switch {
+	case strings.HasPrefix(ref, secretEnv):
+		name := strings.TrimPrefix(ref, secretEnv)
+
+		var exists bool
+		if value, exists = os.LookupEnv(name); !exists {
+			err = fmt.Errorf("%w %s: environment variable is not set", ErrSecret, ref)
+		}
+	case strings.HasPrefix(ref, secretFile):
+		fileName := strings.TrimPrefix(ref, secretFile)
+		if !filepath.IsAbs(fileName) {
+			fileName = filepath.Join(s.dir, fileName)
+		}
+
+		var data []byte
+		if data, err = os.ReadFile(fileName); err != nil {
+			err = fmt.Errorf("%w %s: %w", ErrSecret, ref, err)
+		}
+
+		value = strings.TrimRight(string(data), "\r\n")
+	case strings.HasPrefix(ref, secretCmd):
+		value, err = s.runCmd(ref)
+	default:
+		return ref, nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	s.values[ref] = value
+
+	return value, nil
+}
+
+func (s *secrets) runCmd(ref string) (string, error) {
+	args := strings.Fields(strings.TrimPrefix(ref, secretCmd))
+	if len(args) == 0 {
+		return "", fmt.Errorf("%w %s: no command", ErrSecret, ref)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = s.dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+
+		// Stderr of exec.ExitError is not printed by its Error.
+		return "", fmt.Errorf("%w %s: %w", ErrSecret, ref, err)
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+/*
+checkURI returns an error if the uri credentials contain a secret reference. The uri cannot hold them:
+ssh://admin:env:PW@host is parsed as password env and enable password PW.
+*/
+func checkURI(uri string) error {
+	if uriSecretRef.MatchString(uri) {
+		return fmt.Errorf("%w: references are not supported in uri of host %s, set them in account",
+			ErrSecret, uri[strings.LastIndex(uri, "@")+1:])
+	}
+
+	return nil
+}
+
+// resolveAccount returns the account with the references replaced by the secrets.
+func (s *secrets) resolveAccount(a host.Account) (host.Account, error) {
+	for _, field := range []*string{&a.Username, &a.Password, &a.EnablePassword} {
+		value, err := s.resolve(*field)
+		if err != nil {
+			return a, err
+		}
+
+		*field = value
+	}
+
+	return a, nil
+}
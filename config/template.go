@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const templateDelim = "{{"
+
+/*
+templateData returns the data of the command templates:
+
+	.host      host address
+	.port      host port
+	.username  account username
+	.platform  host platform
+	.vars      host vars over the global ones
+*/
+func (c *HostConfig) templateData(vars map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(vars)+len(c.Vars))
+	for k, v := range vars {
+		merged[k] = v
+	}
+
+	for k, v := range c.Vars {
+		merged[k] = v
+	}
+
+	return map[string]interface{}{
+		"host":     c.Host.Host,
+		"port":     c.Host.Port,
+		"username": c.Host.Username,
+		"platform": c.Platform,
+		"vars":     merged,
+	}
+}
+
+/*
+renderCommands executes the commands as text/template. A command rendered to several lines,
+e.g. with range, gives a command per line. Empty lines are skipped.
+*/
+func renderCommands(commands []string, data map[string]interface{}) ([]string, error) {
+	if commands == nil {
+		return nil, nil
+	}
+
+	rendered := make([]string, 0, len(commands))
+
+	for _, cmd := range commands {
+		if !strings.Contains(cmd, templateDelim) {
+			rendered = append(rendered, cmd)
+			continue
+		}
+
+		t, err := template.New("command").Option("missingkey=error").Parse(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse command template %q: %w", cmd, err)
+		}
+
+		var sb strings.Builder
+		if err = t.Execute(&sb, data); err != nil {
+			return nil, fmt.Errorf("cannot execute command template %q: %w", cmd, err)
+		}
+
+		for _, line := range strings.Split(sb.String(), "\n") {
+			if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+				rendered = append(rendered, line)
+			}
+		}
+	}
+
+	return rendered, nil
+}
+
+func (c *HostConfig) renderCommands(vars map[string]interface{}) (err error) {
+	data := c.templateData(vars)
+
+	if c.InitialCommands, err = renderCommands(c.InitialCommands, data); err != nil {
+		return fmt.Errorf("host %s: %w", c.Host.Host, err)
+	}
+
+	if c.Commands, err = renderCommands(c.Commands, data); err != nil {
+		return fmt.Errorf("host %s: %w", c.Host.Host, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testTemplateConfig = `default_account:
+  username: admin
+  password: password
+vars:
+  domain: example.com
+  uplink: Gi0/1
+initial_commands:
+  - term le 0
+  - "{{if eq .platform \"nxos\"}}term width 511{{end}}"
+commands:
+  - hostname {{.vars.name}}.{{.vars.domain}}
+  - sh int {{.vars.uplink}}
+  - "{{range .vars.vlans}}vlan {{.}}\n{{end}}"
+hosts:
+  - uri: 10.0.0.1
+    platform: ios
+    vars:
+      name: sw1
+      vlans: [10, 20]
+  - uri: 10.0.0.2:2222
+    platform: nxos
+    vars:
+      name: sw2
+      uplink: Eth1/1
+      vlans: []
+    commands:
+      - "sh run | i {{.host}}:{{.port}}"
+`
+
+type TemplateTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TemplateTestSuite) load(data string) (*Config, error) {
+	fileName := path.Join(suite.T().TempDir(), "config.yml")
+	suite.Require().NoError(os.WriteFile(fileName, []byte(data), 0600))
+
+	return Load(fileName, &FromFlags{})
+}
+
+func (suite *TemplateTestSuite) TestRender() {
+	cfg, err := suite.load(testTemplateConfig)
+	suite.Require().NoError(err)
+
+	suite.Equal([]string{"term le 0"}, cfg.Hosts[0].InitialCommands)
+	suite.Equal([]string{"hostname sw1.example.com", "sh int Gi0/1", "vlan 10", "vlan 20"}, cfg.Hosts[0].Commands)
+
+	suite.Equal([]string{"term le 0", "term width 511"}, cfg.Hosts[1].InitialCommands)
+	suite.Equal([]string{"sh run | i 10.0.0.2:2222"}, cfg.Hosts[1].Commands)
+
+	// The global commands are not changed.
+	suite.Equal("hostname {{.vars.name}}.{{.vars.domain}}", cfg.Commands[0])
+}
+
+func (suite *TemplateTestSuite) TestMissingVar() {
+	_, err := suite.load(`default_account:
+  password: password
+commands:
+  - interface {{.vars.uplink}}
+hosts:
+  - 10.0.0.1
+`)
+	suite.ErrorContains(err, "host 10.0.0.1")
+	suite.ErrorContains(err, "uplink")
+}
+
+func (suite *TemplateTestSuite) TestBadTemplate() {
+	_, err := suite.load(`default_account:
+  password: password
+commands:
+  - interface {{.vars.uplink
+hosts:
+  - 10.0.0.1
+`)
+	suite.ErrorContains(err, "cannot parse command template")
+}
+
+func TestTemplateTestSuite(t *testing.T) {
+	suite.Run(t, new(TemplateTestSuite))
+}
@@ -0,0 +1,18 @@
+package console
+
+import (
+	"github.com/jgivc/console/conftree"
+)
+
+/*
+ExecuteConfigTree executes the command, e.g. "show running-config" or "show configuration",
+and parses the output without the command echo and the trailing prompt line into a config tree.
+*/
+func ExecuteConfigTree(c Console, cmd string) (*conftree.Node, error) {
+	out, err := c.Execute(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return conftree.Parse(CommandOutput(cmd, out))
+}
@@ -0,0 +1,212 @@
+/*
+Package conftree parses device configs into a tree of lines: IOS style indented blocks
+and Junos style brace blocks.
+
+	interface Gi0/1          interfaces {
+	 description uplink          ge-0/0/0 {
+	 no shutdown                     description uplink;
+	                             }
+	                         }
+*/
+package conftree
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrSyntax = errors.New("invalid config syntax")
+
+// Node is one config line with the lines nested under it. The root node has empty Line.
+type Node struct {
+	Line     string
+	Children []*Node
+	parent   *Node
+}
+
+func (n *Node) add(line string) *Node {
+	child := &Node{Line: line, parent: n}
+	n.Children = append(n.Children, child)
+
+	return child
+}
+
+// Path returns the lines from the top level node to n.
+func (n *Node) Path() []string {
+	var path []string
+	for ; n != nil && n.parent != nil; n = n.parent {
+		path = append([]string{n.Line}, path...)
+	}
+
+	return path
+}
+
+// Lookup returns the node at the path of exact lines, or nil if there is none.
+func (n *Node) Lookup(path ...string) *Node {
+	for _, line := range path {
+		var next *Node
+
+		for _, child := range n.Children {
+			if child.Line == line {
+				next = child
+				break
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		n = next
+	}
+
+	return n
+}
+
+// Select returns all nodes at the path of regexes, e.g. Select(`^interface Gi`, `^ip address`).
+func (n *Node) Select(patterns ...string) ([]*Node, error) {
+	nodes := []*Node{n}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile path: %w", err)
+		}
+
+		var next []*Node
+
+		for _, node := range nodes {
+			for _, child := range node.Children {
+				if re.MatchString(child.Line) {
+					next = append(next, child)
+				}
+			}
+		}
+
+		nodes = next
+	}
+
+	return nodes, nil
+}
+
+// Walk calls f for every node under n in config order.
+func (n *Node) Walk(f func(node *Node)) {
+	for _, child := range n.Children {
+		f(child)
+		child.Walk(f)
+	}
+}
+
+// Lines returns the lines of all nodes under n.
+func (n *Node) Lines() []string {
+	var lines []string
+
+	n.Walk(func(node *Node) {
+		lines = append(lines, node.Line)
+	})
+
+	return lines
+}
+
+func (n *Node) write(sb *strings.Builder, level int) {
+	for _, child := range n.Children {
+		sb.WriteString(strings.Repeat(" ", level))
+		sb.WriteString(child.Line)
+		sb.WriteString("\n")
+		child.write(sb, level+1)
+	}
+}
+
+// String returns the tree under n in IOS indented style.
+func (n *Node) String() string {
+	var sb strings.Builder
+	n.write(&sb, 0)
+
+	return sb.String()
+}
+
+func splitLines(text string) []string {
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+/*
+ParseIndented parses IOS style config, a line is nested under the previous less indented line.
+Empty lines and "!" comments are skipped.
+*/
+func ParseIndented(text string) *Node {
+	root := &Node{}
+
+	type level struct {
+		node   *Node
+		indent int
+	}
+
+	stack := []level{{node: root, indent: -1}}
+
+	for _, line := range splitLines(text) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "!") {
+			continue
+		}
+
+		i := indent(line)
+		for stack[len(stack)-1].indent >= i {
+			stack = stack[:len(stack)-1]
+		}
+
+		node := stack[len(stack)-1].node.add(trimmed)
+		stack = append(stack, level{node: node, indent: i})
+	}
+
+	return root
+}
+
+/*
+ParseBraces parses Junos style config: "name {" opens a block, "}" closes it and
+the trailing ";" of statements is removed. Empty lines and "#" comments are skipped.
+*/
+func ParseBraces(text string) (*Node, error) {
+	root := &Node{}
+	current := root
+
+	for i, line := range splitLines(text) {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case trimmed == "}":
+			if current == root {
+				return nil, fmt.Errorf("%w: line %d: unexpected }", ErrSyntax, i+1)
+			}
+
+			current = current.parent
+		case strings.HasSuffix(trimmed, "{"):
+			current = current.add(strings.TrimSpace(strings.TrimSuffix(trimmed, "{")))
+		default:
+			current.add(strings.TrimSuffix(trimmed, ";"))
+		}
+	}
+
+	if current != root {
+		return nil, fmt.Errorf("%w: unclosed block %s", ErrSyntax, strings.Join(current.Path(), " > "))
+	}
+
+	return root, nil
+}
+
+// Parse parses the config as Junos style if a line ends with "{", otherwise as IOS style.
+func Parse(text string) (*Node, error) {
+	for _, line := range splitLines(text) {
+		if strings.HasSuffix(strings.TrimSpace(line), "{") {
+			return ParseBraces(text)
+		}
+	}
+
+	return ParseIndented(text), nil
+}
@@ -0,0 +1,140 @@
+package conftree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const showRun = `Building configuration...
+!
+hostname sw1
+!
+interface GigabitEthernet0/1
+ description uplink
+ shutdown
+!
+interface GigabitEthernet0/2
+ description server
+!
+router bgp 65000
+ neighbor 10.0.0.2 remote-as 65001
+ address-family ipv4
+  neighbor 10.0.0.2 activate
+ exit-address-family
+!
+end
+`
+
+const showConfiguration = `## Last commit: 2023-01-01 10:00:00 UTC by admin
+system {
+    host-name r1;
+    services {
+        ssh;
+    }
+}
+interfaces {
+    ge-0/0/0 {
+        description uplink;
+    }
+}
+`
+
+type ConfTreeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ConfTreeTestSuite) TestParseIndented() {
+	root := ParseIndented(showRun)
+
+	suite.Equal([]string{"description uplink", "shutdown"}, root.Lookup("interface GigabitEthernet0/1").Lines())
+	suite.NotNil(root.Lookup("router bgp 65000", "address-family ipv4", "neighbor 10.0.0.2 activate"))
+	suite.NotNil(root.Lookup("router bgp 65000", "exit-address-family"))
+	suite.Nil(root.Lookup("interface GigabitEthernet0/3"))
+
+	node := root.Lookup("router bgp 65000", "address-family ipv4")
+	suite.Equal([]string{"router bgp 65000", "address-family ipv4"}, node.Path())
+
+	suite.Equal(`Building configuration...
+hostname sw1
+interface GigabitEthernet0/1
+ description uplink
+ shutdown
+interface GigabitEthernet0/2
+ description server
+router bgp 65000
+ neighbor 10.0.0.2 remote-as 65001
+ address-family ipv4
+  neighbor 10.0.0.2 activate
+ exit-address-family
+end
+`, root.String())
+}
+
+func (suite *ConfTreeTestSuite) TestParseBraces() {
+	root, err := Parse(showConfiguration)
+	suite.Require().NoError(err)
+
+	suite.NotNil(root.Lookup("system", "services", "ssh"))
+	suite.NotNil(root.Lookup("interfaces", "ge-0/0/0", "description uplink"))
+	suite.Equal("system\n host-name r1\n services\n  ssh\ninterfaces\n ge-0/0/0\n  description uplink\n", root.String())
+
+	_, err = ParseBraces("system {\n")
+	suite.ErrorIs(err, ErrSyntax)
+
+	_, err = ParseBraces("}\n")
+	suite.ErrorIs(err, ErrSyntax)
+}
+
+func (suite *ConfTreeTestSuite) TestSelect() {
+	root := ParseIndented(showRun)
+
+	nodes, err := root.Select(`^interface Gi`, `^description`)
+	suite.Require().NoError(err)
+	suite.Len(nodes, 2)
+	suite.Equal("description server", nodes[1].Line)
+	suite.Equal("interface GigabitEthernet0/2", nodes[1].Path()[0])
+
+	_, err = root.Select(`(`)
+	suite.Error(err)
+}
+
+func (suite *ConfTreeTestSuite) TestDiff() {
+	a := ParseIndented(showRun)
+	b := ParseIndented(`hostname sw1
+interface GigabitEthernet0/2
+ description server
+interface GigabitEthernet0/1
+ description uplink
+ no shutdown
+interface GigabitEthernet0/3
+ description new
+router bgp 65000
+ neighbor 10.0.0.2 remote-as 65001
+ address-family ipv4
+  neighbor 10.0.0.2 activate
+ exit-address-family
+end
+`)
+
+	changes := Diff(a, b)
+	suite.Require().Len(changes, 4)
+	suite.Equal("- Building configuration...", changes[0].String())
+	suite.Equal("- interface GigabitEthernet0/1 > shutdown", changes[1].String())
+	suite.Equal("+ interface GigabitEthernet0/1 > no shutdown", changes[2].String())
+	suite.Equal("+ interface GigabitEthernet0/3", changes[3].String())
+
+	suite.Equal(`- Building configuration...
+  interface GigabitEthernet0/1
+-  shutdown
++  no shutdown
++ interface GigabitEthernet0/3
++  description new
+`, FormatDiff(changes))
+
+	suite.Empty(Diff(a, ParseIndented(showRun)))
+}
+
+func TestConfTreeTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfTreeTestSuite))
+}
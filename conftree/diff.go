@@ -0,0 +1,100 @@
+package conftree
+
+import (
+	"strings"
+)
+
+const (
+	Added   = "+"
+	Removed = "-"
+)
+
+// Change is a line added or removed with all the lines under it.
+type Change struct {
+	Kind string // Added or Removed
+	Path []string
+	Node *Node
+}
+
+// String returns the change as "+ interface Gi0/1 > description uplink".
+func (c *Change) String() string {
+	return c.Kind + " " + strings.Join(append(append([]string{}, c.Path...), c.Node.Line), " > ")
+}
+
+/*
+Diff returns the changes from a to b. The order of the sibling lines is ignored,
+so moved blocks are not reported. Repeated sibling lines are matched in order.
+*/
+func Diff(a, b *Node) []Change {
+	var changes []Change
+	diff(a, b, nil, &changes)
+
+	return changes
+}
+
+func diff(a, b *Node, path []string, changes *[]Change) {
+	others := make(map[string][]*Node)
+	for _, child := range b.Children {
+		others[child.Line] = append(others[child.Line], child)
+	}
+
+	matched := make(map[*Node]bool)
+
+	for _, child := range a.Children {
+		candidates := others[child.Line]
+		if len(candidates) == 0 {
+			*changes = append(*changes, Change{Kind: Removed, Path: path, Node: child})
+			continue
+		}
+
+		other := candidates[0]
+		others[child.Line] = candidates[1:]
+		matched[other] = true
+
+		diff(child, other, append(path[:len(path):len(path)], child.Line), changes)
+	}
+
+	for _, child := range b.Children {
+		if !matched[child] {
+			*changes = append(*changes, Change{Kind: Added, Path: path, Node: child})
+		}
+	}
+}
+
+/*
+FormatDiff returns the changes as an indented tree of the changed blocks:
+
+	  interface Gi0/1
+	-  shutdown
+	+  description uplink
+	+ interface Gi0/2
+	+  description server
+*/
+func FormatDiff(changes []Change) string {
+	var (
+		sb   strings.Builder
+		last []string
+	)
+
+	for _, c := range changes {
+		// Write the parent lines that differ from the previous change.
+		common := 0
+		for common < len(last) && common < len(c.Path) && last[common] == c.Path[common] {
+			common++
+		}
+
+		for i := common; i < len(c.Path); i++ {
+			sb.WriteString("  " + strings.Repeat(" ", i) + c.Path[i] + "\n")
+		}
+
+		last = c.Path
+
+		sb.WriteString(c.Kind + " " + strings.Repeat(" ", len(c.Path)) + c.Node.Line + "\n")
+
+		c.Node.Walk(func(node *Node) {
+			sb.WriteString(c.Kind + " " + strings.Repeat(" ", len(c.Path)+len(node.Path())-len(c.Node.Path())) + node.Line + "\n")
+		})
+	}
+
+	return sb.String()
+}
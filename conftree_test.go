@@ -0,0 +1,29 @@
+package console
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigTreeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ConfigTreeTestSuite) TestExecuteConfigTree() {
+	root, err := ExecuteConfigTree(&outputConsole{out: "hostname sw1\ninterface Gi0/1\n shutdown\nsw1#"}, "sh run")
+	suite.Require().NoError(err)
+	suite.Equal("hostname sw1\ninterface Gi0/1\n shutdown\n", root.String())
+
+	root, err = ExecuteConfigTree(&outputConsole{out: "sh run\r\nhostname sw1\r\nsw1#"}, "sh run")
+	suite.Require().NoError(err)
+	suite.Equal("hostname sw1\n", root.String())
+
+	root, err = ExecuteConfigTree(&outputConsole{out: "system {\r\n    host-name r1;\r\n}\r\nadmin@r1> "}, "show configuration")
+	suite.Require().NoError(err)
+	suite.NotNil(root.Lookup("system", "host-name r1"))
+}
+
+func TestConfigTreeTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTreeTestSuite))
+}
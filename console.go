@@ -3,9 +3,12 @@ package console
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jgivc/console/config"
@@ -16,6 +19,10 @@ import (
 
 var (
 	cmdEnd = []byte("\r")
+
+	ErrEnableFailed   = errors.New("cannot enter privileged mode")
+	ErrConnectionLost = util.ErrConnectionLost
+	ErrAuthFailed     = transport.ErrAuthFailed
 )
 
 type TransportFactory interface {
@@ -29,14 +36,25 @@ type promptReader interface {
 	io.Reader
 }
 
+/*
+Console is safe for concurrent use. Commands are funnelled through an internal queue
+and executed one at a time. Open and Close must not be called concurrently with other methods.
+*/
 type Console interface {
 	Open(ctx context.Context, host *host.Host) error
 	Execute(cmd string) (string, error)
+	ExecuteContext(ctx context.Context, cmd string) (string, error)
+	// The console is held by the returned reader until it is read to the end.
 	GetCommandResultReader(cmd string) (io.Reader, error)
 	Run(cmd string) error // Just run command and read and omit output
+	RunContext(ctx context.Context, cmd string) error
 	Send(cmd string) error
 	Sendln(cmd string) error
 	SetPrompt(pattern string) error
+	Enable() error  // Enter privileged mode using EnablePassword if not already there
+	Disable() error // Leave privileged mode if in it
+	Privileged() bool
+	ExecutePrivileged(cmd string) (string, error) // Like Execute, but ensure privileged mode first
 	Close() error
 }
 
@@ -46,6 +64,12 @@ type console struct {
 	transport    transport.Transport
 	promptReader promptReader
 	cfg          *config.ConsoleConfig
+	privileged   atomic.Bool
+	lastActivity atomic.Int64 // UnixNano of the last exchange with the device
+	mu           sync.RWMutex // Protects q and keepAliveErr
+	q            *queue
+	keepAliveErr error // Why the keepalive stopped, it is added to the errors of the next commands
+	keepAlive    *keepAlive
 }
 
 func (c *console) tryAuth() error {
@@ -54,39 +78,48 @@ func (c *console) tryAuth() error {
 	}
 	c.promptReader.SetDeadLine(time.Now().Add(c.cfg.AuthTimeout))
 
-	var enable bool
+	var (
+		buf                        bytes.Buffer
+		usernameSent, passwordSent bool
+	)
 
-	var buf bytes.Buffer
 	for {
 		_, err := buf.ReadFrom(c.promptReader)
 		if err != nil {
 			return fmt.Errorf("auth fail: %w", err)
 		}
 
+		// The device asks for the credentials again if it rejected them.
 		if strings.Contains(strings.ToLower(buf.String()), c.cfg.UsernamePromptContains) {
-			if err2 := c.Sendln(c.host.Username); err2 != nil {
+			if usernameSent {
+				return fmt.Errorf("auth fail: %w", ErrAuthFailed)
+			}
+
+			if err2 := c.sendln(c.host.Username); err2 != nil {
 				return fmt.Errorf("auth fail: %w", err2)
 			}
+			usernameSent = true
 		} else if strings.Contains(strings.ToLower(buf.String()), c.cfg.PasswordPromptContains) {
-			if enable {
-				if err2 := c.Sendln(c.host.EnablePassword); err2 != nil {
-					return fmt.Errorf("auth fail: %w", err2)
-				}
-			} else {
-				if err2 := c.Sendln(c.host.Password); err2 != nil {
-					return fmt.Errorf("auth fail: %w", err2)
-				}
+			if passwordSent {
+				return fmt.Errorf("auth fail: %w", ErrAuthFailed)
 			}
-		} else if strings.HasSuffix(strings.TrimSpace(buf.String()), c.cfg.PromptSuffix) {
-			if err2 := c.promptReader.SetPromptPattern(c.cfg.PromptPattern); err2 != nil {
-				return fmt.Errorf("cannot set promptPattern: %w", err2)
+
+			if err2 := c.sendSecretln(c.host.Password); err2 != nil {
+				return fmt.Errorf("auth fail: %w", err2)
 			}
-			return nil
+			passwordSent = true
+		} else if strings.HasSuffix(strings.TrimSpace(buf.String()), c.cfg.PromptSuffix) {
+			return c.setPrivileged(true)
 		} else if strings.HasSuffix(strings.TrimSpace(buf.String()), c.cfg.EnableSuffix) {
-			enable = true
-			if err2 := c.Sendln(c.cfg.EnableCommand); err2 != nil {
+			if err2 := c.setPrivileged(false); err2 != nil {
+				return err2
+			}
+
+			if err2 := c.enable(); err2 != nil {
 				return fmt.Errorf("auth fail: %w", err2)
 			}
+
+			return nil
 		} else {
 			return fmt.Errorf("cannot login")
 		}
@@ -96,82 +129,430 @@ func (c *console) tryAuth() error {
 	}
 }
 
+// setPrivileged stores the privilege state and switches the prompt pattern to the matching one.
+func (c *console) setPrivileged(privileged bool) error {
+	pattern := c.cfg.PromptPattern
+	if !privileged {
+		pattern = c.cfg.UnprivilegedPromptPattern
+	}
+
+	if err := c.promptReader.SetPromptPattern(pattern); err != nil {
+		return fmt.Errorf("cannot set promptPattern: %w", err)
+	}
+
+	c.privileged.Store(privileged)
+
+	return nil
+}
+
+// anyPromptPattern matches the prompt of both privilege levels.
+func (c *console) anyPromptPattern() string {
+	return fmt.Sprintf("(?:%s)|(?:%s)", c.cfg.PromptPattern, c.cfg.UnprivilegedPromptPattern)
+}
+
+// trackPrivilege updates the privilege state from the prompt at the end of the command output.
+func (c *console) trackPrivilege(out string) {
+	out = strings.TrimSpace(out)
+	if strings.HasSuffix(out, c.cfg.PromptSuffix) {
+		c.privileged.Store(true)
+	} else if strings.HasSuffix(out, c.cfg.EnableSuffix) {
+		c.privileged.Store(false)
+	}
+}
+
+func (c *console) enable() (err error) {
+	if c.privileged.Load() {
+		return nil
+	}
+
+	if err = c.promptReader.SetPromptPattern(c.cfg.AuthPromptPattern); err != nil {
+		return fmt.Errorf("cannot set authPromptPattern: %w", err)
+	}
+
+	// The prompt pattern of the resulting privilege level is restored on every exit path.
+	defer func() {
+		if errPattern := c.setPrivileged(c.privileged.Load()); err == nil {
+			err = errPattern
+		}
+	}()
+
+	c.promptReader.SetDeadLine(time.Now().Add(c.cfg.AuthTimeout))
+	c.promptReader.Reset()
+
+	if err = c.sendln(c.cfg.EnableCommand); err != nil {
+		return fmt.Errorf("cannot enable: %w", err)
+	}
+
+	var (
+		buf          bytes.Buffer
+		passwordSent bool
+	)
+
+	for {
+		if _, err = buf.ReadFrom(c.promptReader); err != nil {
+			return fmt.Errorf("cannot enable: %w", err)
+		}
+
+		if strings.Contains(strings.ToLower(buf.String()), c.cfg.PasswordPromptContains) {
+			if passwordSent {
+				return ErrEnableFailed
+			}
+
+			if err = c.sendSecretln(c.host.EnablePassword); err != nil {
+				return fmt.Errorf("cannot enable: %w", err)
+			}
+			passwordSent = true
+		} else if strings.HasSuffix(strings.TrimSpace(buf.String()), c.cfg.PromptSuffix) {
+			c.privileged.Store(true)
+			return nil
+		} else if strings.HasSuffix(strings.TrimSpace(buf.String()), c.cfg.EnableSuffix) {
+			c.privileged.Store(false)
+			return ErrEnableFailed
+		} else {
+			return fmt.Errorf("cannot enable: unexpected output: %q", buf.String())
+		}
+
+		c.promptReader.Reset()
+		buf.Reset()
+	}
+}
+
+func (c *console) disable() error {
+	if !c.privileged.Load() {
+		return nil
+	}
+
+	if err := c.setPrivileged(false); err != nil {
+		return err
+	}
+	c.promptReader.SetDeadLine(time.Now().Add(c.cfg.ExecTimeout))
+	c.promptReader.Reset()
+
+	if err := c.sendln(c.cfg.DisableCommand); err != nil {
+		return fmt.Errorf("cannot disable: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(c.promptReader); err != nil {
+		return fmt.Errorf("cannot disable: %w", err)
+	}
+
+	return nil
+}
+
+func (c *console) Privileged() bool {
+	return c.privileged.Load()
+}
+
+func (c *console) ExecutePrivileged(cmd string) (string, error) {
+	var out string
+
+	// out is written by the queue goroutine, it is read only after the job is done.
+	if err := c.do(context.Background(), func() error {
+		if err := c.enable(); err != nil {
+			return err
+		}
+
+		var err error
+		out, err = c.execute(context.Background(), cmd)
+
+		return err
+	}); err != nil {
+		return "", err
+	}
+
+	return out, nil
+}
+
+func (c *console) Enable() error {
+	return c.do(context.Background(), c.enable)
+}
+
+func (c *console) Disable() error {
+	return c.do(context.Background(), c.disable)
+}
+
+func (c *console) queue() *queue {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.q
+}
+
+// do runs f in the console queue, so it is never interleaved with other commands or keepalives.
+func (c *console) do(ctx context.Context, f func() error) error {
+	q := c.queue()
+	if q == nil {
+		return fmt.Errorf("%w: console is not open", ErrConnectionLost)
+	}
+
+	if err := q.do(ctx, &job{ctx: ctx, f: f}); err != nil {
+		c.mu.RLock()
+		errKeepAlive := c.keepAliveErr
+		c.mu.RUnlock()
+
+		if errKeepAlive != nil {
+			return fmt.Errorf("%w (keepalive stopped: %w)", err, errKeepAlive)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *console) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+func (c *console) idle() time.Duration {
+	return time.Since(time.Unix(0, c.lastActivity.Load()))
+}
+
+// setDeadLine sets the deadline of the prompt reader for the job: the ctx deadline or ExecTimeout from now.
+func (c *console) setDeadLine(ctx context.Context) {
+	deadLine, ok := ctx.Deadline()
+	if !ok {
+		deadLine = time.Now().Add(c.cfg.ExecTimeout)
+	}
+
+	c.promptReader.SetDeadLine(deadLine)
+}
+
 func (c *console) Open(ctx context.Context, host *host.Host) error {
-	var err error
-	c.transport, err = c.factory.GetTransport(host)
+	tr, err := c.factory.GetTransport(host)
 	if err != nil {
 		return err
 	}
 
-	if err2 := c.transport.Open(ctx, host); err2 != nil {
+	if err2 := tr.Open(ctx, host); err2 != nil {
 		return err2
 	}
 
+	c.transport = tr
+
 	c.host = host
 	c.promptReader = util.NewPromptReader(c.transport, c.cfg.TransportReaderBufferSize, c.cfg.PromptMatchLengt)
 
-	return c.tryAuth()
+	if err = c.tryAuth(); err != nil {
+		return err
+	}
+
+	c.touch()
+	c.mu.Lock()
+	c.q = newQueue(c.touch)
+	c.keepAliveErr = nil
+	c.mu.Unlock()
+
+	if c.cfg.KeepAliveInterval > 0 {
+		c.keepAlive = startKeepAlive(c, c.cfg.KeepAliveInterval)
+	}
+
+	return nil
 }
 
 func (c *console) Execute(cmd string) (string, error) {
+	return c.ExecuteContext(context.Background(), cmd)
+}
+
+func (c *console) ExecuteContext(ctx context.Context, cmd string) (string, error) {
+	var out string
+
+	// out is written by the queue goroutine, it is read only after the job is done.
+	if err := c.do(ctx, func() error {
+		var err error
+		out, err = c.execute(ctx, cmd)
+
+		return err
+	}); err != nil {
+		return "", err
+	}
+
+	return out, nil
+}
+
+func (c *console) execute(ctx context.Context, cmd string) (out string, err error) {
+	// The command can change the privilege level, e.g. disable, so the prompt of any level ends the output.
+	if err = c.promptReader.SetPromptPattern(c.anyPromptPattern()); err != nil {
+		return "", fmt.Errorf("cannot set promptPattern: %w", err)
+	}
+
+	// The prompt pattern of the resulting privilege level is restored on every exit path.
+	defer func() {
+		if errPattern := c.setPrivileged(c.privileged.Load()); err == nil {
+			err = errPattern
+		}
+	}()
+
+	c.setDeadLine(ctx)
 	c.promptReader.Reset()
-	if err := c.Sendln(cmd); err != nil {
+	if err = c.sendln(cmd); err != nil {
 		return "", fmt.Errorf("cannot execute cmd: %w", err)
 	}
 
 	var buf bytes.Buffer
-	if _, err := buf.ReadFrom(c.promptReader); err != nil {
+	if _, err = buf.ReadFrom(c.promptReader); err != nil {
 		return "", fmt.Errorf("cannot execute cmd: %w", err)
 	}
+	c.trackPrivilege(buf.String())
 
 	return buf.String(), nil
 }
 
 func (c *console) GetCommandResultReader(cmd string) (io.Reader, error) {
-	c.promptReader.Reset()
-	if err := c.Sendln(cmd); err != nil {
-		return nil, fmt.Errorf("cannot execute cmd: %w", err)
+	q := c.queue()
+	if q == nil {
+		return nil, fmt.Errorf("%w: console is not open", ErrConnectionLost)
 	}
 
-	return c.promptReader, nil
-}
+	r := &resultReader{
+		c:        c,
+		released: make(chan struct{}),
+	}
 
-func (c *console) Run(cmd string) error {
-	c.promptReader.Reset()
-	if err := c.Sendln(cmd); err != nil {
-		return fmt.Errorf("cannot execute cmd: %w", err)
+	err := q.do(context.Background(), &job{
+		ctx: context.Background(),
+		f: func() error {
+			c.promptReader.Reset()
+			if err := c.sendln(cmd); err != nil {
+				r.release()
+				return fmt.Errorf("cannot execute cmd: %w", err)
+			}
+
+			return nil
+		},
+		hold: r.released,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var buf bytes.Buffer
-	if _, err := buf.ReadFrom(c.promptReader); err != nil {
-		return fmt.Errorf("cannot execute cmd: %w", err)
+	return r, nil
+}
+
+// resultReader holds the console queue until the command result is read completely.
+type resultReader struct {
+	c        *console
+	released chan struct{}
+	once     sync.Once
+}
+
+func (r *resultReader) release() {
+	r.once.Do(func() {
+		close(r.released)
+	})
+}
+
+func (r *resultReader) Read(p []byte) (int, error) {
+	n, err := r.c.promptReader.Read(p)
+	r.c.touch()
+
+	if err != nil {
+		r.release()
 	}
 
-	return nil
+	return n, err
 }
 
-func (c *console) Send(cmd string) error {
-	_, err := c.transport.Write([]byte(cmd))
+func (c *console) Run(cmd string) error {
+	return c.RunContext(context.Background(), cmd)
+}
+
+func (c *console) RunContext(ctx context.Context, cmd string) error {
+	return c.do(ctx, func() error {
+		return c.run(ctx, cmd)
+	})
+}
+
+func (c *console) run(ctx context.Context, cmd string) error {
+	_, err := c.execute(ctx, cmd)
 
 	return err
 }
 
-func (c *console) Sendln(cmd string) error {
+func (c *console) Send(cmd string) error {
+	return c.do(context.Background(), func() error {
+		return c.send(cmd)
+	})
+}
+
+func (c *console) send(cmd string) error {
+	if c.transport == nil {
+		return fmt.Errorf("%w: console is closed", ErrConnectionLost)
+	}
+
 	if _, err := c.transport.Write([]byte(cmd)); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+	}
+
+	return nil
+}
+
+func (c *console) Sendln(cmd string) error {
+	return c.do(context.Background(), func() error {
+		return c.sendln(cmd)
+	})
+}
+
+func (c *console) sendln(cmd string) error {
+	if err := c.send(cmd); err != nil {
 		return err
 	}
 
-	_, err := c.transport.Write(cmdEnd)
+	if _, err := c.transport.Write(cmdEnd); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+	}
 
-	return err
+	return nil
+}
+
+// sendSecretln sends the password, so it is masked if the session is recorded.
+func (c *console) sendSecretln(secret string) error {
+	sw, ok := c.transport.(transport.SecretWriter)
+	if !ok {
+		return c.sendln(secret)
+	}
+
+	if _, err := sw.WriteSecret([]byte(secret)); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+	}
+
+	if _, err := c.transport.Write(cmdEnd); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+	}
+
+	return nil
 }
 
 func (c *console) SetPrompt(pattern string) error {
-	return c.promptReader.SetPromptPattern(pattern)
+	return c.do(context.Background(), func() error {
+		return c.promptReader.SetPromptPattern(pattern)
+	})
 }
 
 func (c *console) Close() error {
-	return c.transport.Close()
+	if c.keepAlive != nil {
+		c.keepAlive.Stop()
+		c.keepAlive = nil
+	}
+
+	c.mu.Lock()
+	q := c.q
+	c.q = nil
+	c.mu.Unlock()
+
+	if q != nil {
+		q.stop()
+	}
+
+	if c.transport == nil {
+		return nil
+	}
+
+	err := c.transport.Close()
+	c.transport = nil
+
+	return err
 }
 
 func New() Console {
@@ -185,6 +566,9 @@ func NewWithConfig(cfg *config.ConsoleConfig) Console {
 			DummyFileName: cfg.DummyTransportFileName,
 			ReadTimeout:   cfg.TransportReadTimeout,
 			BufSize:       cfg.TransportReaderBufferSize,
+			RecordDir:     cfg.RecordDir,
+			RecordFormat:  cfg.RecordFormat,
+			CaptureDir:    cfg.CaptureDir,
 		},
 	}
 }
@@ -2,7 +2,9 @@ package console
 
 import (
 	"context"
+	"io"
 	"os"
+	"path"
 	"testing"
 	"time"
 
@@ -144,6 +146,256 @@ func (suite *ConsoleTestSuite) TestOpenSuccess() {
 	suite.transport.AssertExpectations(suite.T())
 }
 
+func (suite *ConsoleTestSuite) onRead(data string) {
+	suite.transport.On("Read", mock.Anything).Return(len(data), nil).Run(func(args mock.Arguments) {
+		b, ok := args.Get(0).([]byte)
+		if !ok {
+			suite.T().Fatal("cannot convert")
+		}
+		copy(b, data)
+	}).Once()
+	suite.transport.On("Read", mock.Anything).Return(0, os.ErrDeadlineExceeded).Once()
+}
+
+func (suite *ConsoleTestSuite) onWrite(written *[]string) {
+	suite.transport.On("Write", mock.Anything).Return(0, nil).Run(func(args mock.Arguments) {
+		b, ok := args.Get(0).([]byte)
+		if !ok {
+			suite.T().Fatal("cannot convert")
+		}
+
+		if string(b) != string(cmdEnd) {
+			*written = append(*written, string(b))
+		}
+	})
+}
+
+func (suite *ConsoleTestSuite) TestOpenEnable() {
+	var written []string
+
+	suite.transport.On("Open", mock.Anything, mock.Anything).Return(nil)
+	suite.onRead("Username: ")
+	suite.onRead("Password: ")
+	suite.onRead("sw1>")
+	suite.onRead("sw1>en\nPassword: ")
+	suite.onRead("sw1#")
+	suite.transport.On("Read", mock.Anything).Return(0, os.ErrDeadlineExceeded).Maybe()
+	suite.onWrite(&written)
+
+	suite.factory.On("GetTransport", mock.Anything).Return(suite.transport, nil)
+	err := suite.console.Open(context.Background(), &host.Host{
+		Account: host.Account{
+			Username:       "user",
+			Password:       "pass",
+			EnablePassword: "secret",
+		},
+	})
+	suite.NoError(err)
+	suite.True(suite.console.Privileged())
+	suite.Equal([]string{"user", "pass", "en", "secret"}, written)
+}
+
+func (suite *ConsoleTestSuite) TestEnableFailed() {
+	var written []string
+
+	suite.transport.On("Open", mock.Anything, mock.Anything).Return(nil)
+	suite.onRead("Password: ")
+	suite.onRead("sw1>")
+	suite.onRead("sw1>en\nPassword: ")
+	suite.onRead("% Access denied\nsw1>")
+	suite.transport.On("Read", mock.Anything).Return(0, os.ErrDeadlineExceeded).Maybe()
+	suite.onWrite(&written)
+
+	suite.factory.On("GetTransport", mock.Anything).Return(suite.transport, nil)
+	err := suite.console.Open(context.Background(), &host.Host{
+		Account: host.Account{
+			Password:       "pass",
+			EnablePassword: "wrong",
+		},
+	})
+	suite.ErrorIs(err, ErrEnableFailed)
+	suite.False(suite.console.Privileged())
+}
+
+func (suite *ConsoleTestSuite) TestDisableAndExecutePrivileged() {
+	var written []string
+
+	suite.transport.On("Open", mock.Anything, mock.Anything).Return(nil)
+	suite.onRead("Password: ")
+	suite.onRead("sw1#")
+	suite.onRead("sw1#disable\nsw1>")
+	suite.onRead("sw1>en\nPassword: ")
+	suite.onRead("sw1#")
+	suite.onRead("sw1#sh run\nhostname sw1\nsw1#")
+	suite.transport.On("Read", mock.Anything).Return(0, os.ErrDeadlineExceeded).Maybe()
+	suite.onWrite(&written)
+
+	suite.factory.On("GetTransport", mock.Anything).Return(suite.transport, nil)
+	err := suite.console.Open(context.Background(), &host.Host{
+		Account: host.Account{
+			Password:       "pass",
+			EnablePassword: "secret",
+		},
+	})
+	suite.Require().NoError(err)
+	suite.True(suite.console.Privileged())
+
+	suite.Require().NoError(suite.console.Disable())
+	suite.False(suite.console.Privileged())
+
+	out, err := suite.console.ExecutePrivileged("sh run")
+	suite.Require().NoError(err)
+	suite.Contains(out, "hostname sw1")
+	suite.True(suite.console.Privileged())
+	suite.Equal([]string{"pass", "disable", "en", "secret", "sh run"}, written)
+}
+
+const testHostileScenario = `<scenario fragment="2" fragment_random="true" byte_latency="1ms" jitter="3ms" seed="7">
+    <send>Username: </send>
+    <expect>admin</expect>
+    <send>Password: </send>
+    <expect>secret</expect>
+    <send>sw1#</send>
+    <expect>show version</expect>
+    <send>Version 15.2&#10;sw1#</send>
+    <expect>show run</expect>
+    <error>connection reset by peer</error>
+</scenario>`
+
+// TestHostileLink runs the console over the dummy transport that splits the output
+// into 1-2 byte fragments, so prompts are split across reads and reads time out in between.
+func (suite *ConsoleTestSuite) TestHostileLink() {
+	fileName := path.Join(suite.T().TempDir(), "sw1.xml")
+	suite.Require().NoError(os.WriteFile(fileName, []byte(testHostileScenario), 0600))
+
+	suite.console.cfg.TransportReadTimeout = 2 * time.Millisecond
+	suite.console.factory = &transport.Factory{
+		DummyFileName: fileName,
+		ReadTimeout:   suite.console.cfg.TransportReadTimeout,
+	}
+
+	h := &host.Host{
+		TransportType: transport.TransportDummy,
+		Account:       host.Account{Username: "admin", Password: "secret"},
+	}
+	suite.Require().NoError(suite.console.Open(context.Background(), h))
+	defer suite.console.Close()
+
+	out, err := suite.console.Execute("show version")
+	suite.Require().NoError(err)
+	suite.Equal("Version 15.2\nsw1#", out)
+
+	_, err = suite.console.Execute("show run")
+	suite.ErrorIs(err, ErrConnectionLost)
+}
+
+const testPrivilegeScenario = `<scenario>
+    <send>Password: </send>
+    <expect>secret</expect>
+    <send>sw1#</send>
+    <expect>disable</expect>
+    <send>sw1></send>
+    <expect>show version</expect>
+    <send>Version 15.2&#10;sw1></send>
+    <expect>enable 15</expect>
+    <send>sw1#</send>
+    <expect>show run</expect>
+    <send>hostname sw1&#10;sw1#</send>
+</scenario>`
+
+// TestExecuteChangesPrivilege changes the privilege level with commands, not with Enable and Disable.
+func (suite *ConsoleTestSuite) TestExecuteChangesPrivilege() {
+	fileName := path.Join(suite.T().TempDir(), "sw1.xml")
+	suite.Require().NoError(os.WriteFile(fileName, []byte(testPrivilegeScenario), 0600))
+
+	suite.console.cfg.ExecTimeout = time.Second
+	suite.console.factory = &transport.Factory{
+		DummyFileName: fileName,
+		ReadTimeout:   suite.console.cfg.TransportReadTimeout,
+	}
+
+	h := &host.Host{
+		TransportType: transport.TransportDummy,
+		Account:       host.Account{Password: "secret"},
+	}
+	suite.Require().NoError(suite.console.Open(context.Background(), h))
+	defer suite.console.Close()
+	suite.True(suite.console.Privileged())
+
+	out, err := suite.console.Execute("disable")
+	suite.Require().NoError(err)
+	suite.Equal("sw1>", out)
+	suite.False(suite.console.Privileged())
+
+	out, err = suite.console.Execute("show version")
+	suite.Require().NoError(err)
+	suite.Equal("Version 15.2\nsw1>", out)
+
+	_, err = suite.console.Execute("enable 15")
+	suite.Require().NoError(err)
+	suite.True(suite.console.Privileged())
+
+	out, err = suite.console.Execute("show run")
+	suite.Require().NoError(err)
+	suite.Equal("hostname sw1\nsw1#", out)
+}
+
+// scriptedReader returns the chunks one per ReadFrom and records the prompt patterns.
+type scriptedReader struct {
+	chunks   []string
+	eof      bool
+	patterns []string
+}
+
+func (r *scriptedReader) SetPromptPattern(pattern string) error {
+	r.patterns = append(r.patterns, pattern)
+	return nil
+}
+
+func (r *scriptedReader) SetDeadLine(deadLine time.Time) {}
+func (r *scriptedReader) Reset()                         {}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	if r.eof || len(r.chunks) == 0 {
+		r.eof = false
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	r.eof = true
+
+	return n, nil
+}
+
+type writeTransport struct {
+	transport.Transport
+}
+
+func (t *writeTransport) Write(b []byte) (int, error) { return len(b), nil }
+
+func (suite *ConsoleTestSuite) TestEnableRestoresPattern() {
+	cfg := config.DefaultConsoleConfig()
+
+	for _, chunks := range [][]string{
+		{"en\nPassword: ", "% Access denied\nPassword: "},
+		{"en\nPassword: ", "% Access denied\nsw1>"},
+		{"en\n% Unexpected"},
+	} {
+		r := &scriptedReader{chunks: chunks}
+		c := &console{
+			cfg:          cfg,
+			host:         &host.Host{Account: host.Account{EnablePassword: "wrong"}},
+			transport:    &writeTransport{},
+			promptReader: r,
+		}
+
+		suite.Error(c.enable())
+		suite.False(c.Privileged())
+		suite.Equal(cfg.UnprivilegedPromptPattern, r.patterns[len(r.patterns)-1], chunks)
+	}
+}
+
 func TestConsoleTestSuite(t *testing.T) {
 	suite.Run(t, new(ConsoleTestSuite))
 }
@@ -0,0 +1,235 @@
+// Package diff produces unified diffs of text lines.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const DefaultContext = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is one line of the edit script. a and b are the line indexes in the old and new text.
+type op struct {
+	kind opKind
+	a, b int
+}
+
+// Lines splits the text into lines without line endings. \r\n is treated as \n.
+func Lines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+/*
+editScript returns the shortest edit script from a to b (E. Myers, An O(ND) Difference Algorithm).
+The linear space refinement is used: the middle snake of the shortest path splits the texts in two,
+which are compared recursively, so the memory is O(N+M) even if every line differs.
+*/
+func editScript(a, b []string) []op {
+	s := &script{a: a, b: b, ops: make([]op, 0, len(a)+len(b))}
+	s.compare(0, len(a), 0, len(b))
+
+	return s.ops
+}
+
+type script struct {
+	a, b []string
+	ops  []op
+}
+
+// compare adds the edit script from a[aLo:aHi] to b[bLo:bHi].
+func (s *script) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && s.a[aLo] == s.b[bLo] {
+		s.ops = append(s.ops, op{opEqual, aLo, bLo})
+		aLo++
+		bLo++
+	}
+
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && s.a[aHi-suffix-1] == s.b[bHi-suffix-1] {
+		suffix++
+	}
+
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			s.ops = append(s.ops, op{opInsert, aLo, y})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			s.ops = append(s.ops, op{opDelete, x, bLo})
+		}
+	default:
+		// Both halves have edits, as the texts differ at the first and the last line.
+		x, y := s.middleSnake(aLo, aHi, bLo, bHi)
+		s.compare(aLo, x, bLo, y)
+		s.compare(x, aHi, y, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		s.ops = append(s.ops, op{opEqual, aHi + i, bHi + i})
+	}
+}
+
+// middleSnake returns the point where the forward and the reverse shortest paths meet.
+func (s *script) middleSnake(aLo, aHi, bLo, bHi int) (int, int) {
+	a, b := s.a[aLo:aHi], s.b[bLo:bHi]
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	delta := n - m
+	odd := delta%2 != 0
+
+	// vf is the furthest x of the forward paths on the diagonals, vr of the reverse ones from the end.
+	vf := make([]int, 2*offset+1)
+	vr := make([]int, 2*offset+1)
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			vf[offset+k] = x
+
+			// The reverse path of d-1 on the same diagonal.
+			if rk := delta - k; odd && rk >= -(d-1) && rk <= d-1 && x+vr[offset+rk] >= n {
+				return aLo + x, bLo + y
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && vr[offset+k-1] < vr[offset+k+1]) {
+				x = vr[offset+k+1]
+			} else {
+				x = vr[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+
+			vr[offset+k] = x
+
+			if fk := delta - k; !odd && fk >= -d && fk <= d && x+vf[offset+fk] >= n {
+				return aLo + n - x, bLo + m - y
+			}
+		}
+	}
+
+	// Not reached: the paths meet at d <= maxD.
+	return aHi, bHi
+}
+
+// hunkRange formats the range of the hunk header, start is 0-based.
+// An empty range is written as the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+/*
+Unified returns the unified diff of the old text a and the new text b
+with context lines around the changes. It returns an empty string if the texts are equal.
+*/
+func Unified(aName, bName string, a, b []string, context int) string {
+	ops := editScript(a, b)
+
+	var changes []int
+	for i, o := range ops {
+		if o.kind != opEqual {
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+
+	for i := 0; i < len(changes); {
+		// Join the changes closer than 2*context lines into one hunk.
+		first, last := changes[i], changes[i]
+		for i++; i < len(changes) && changes[i]-last <= 2*context; i++ {
+			last = changes[i]
+		}
+
+		start := first - context
+		if start < 0 {
+			start = 0
+		}
+
+		end := last + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		writeHunk(&sb, ops[start:end], a, b)
+	}
+
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []op, a, b []string) {
+	aStart, bStart := ops[0].a, ops[0].b
+	aCount, bCount := 0, 0
+
+	for _, o := range ops {
+		if o.kind != opInsert {
+			aCount++
+		}
+
+		if o.kind != opDelete {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			sb.WriteString(" " + a[o.a] + "\n")
+		case opDelete:
+			sb.WriteString("-" + a[o.a] + "\n")
+		case opInsert:
+			sb.WriteString("+" + b[o.b] + "\n")
+		}
+	}
+}
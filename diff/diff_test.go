@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DiffTestSuite struct {
+	suite.Suite
+}
+
+func (suite *DiffTestSuite) TestLines() {
+	suite.Nil(Lines(""))
+	suite.Equal([]string{"a", "b"}, Lines("a\r\nb\r\n"))
+	suite.Equal([]string{"a", "", "b"}, Lines("a\n\nb"))
+}
+
+func (suite *DiffTestSuite) TestEqual() {
+	suite.Empty(Unified("a", "b", Lines("x\ny\n"), Lines("x\ny\n"), DefaultContext))
+	suite.Empty(Unified("a", "b", nil, nil, DefaultContext))
+}
+
+func (suite *DiffTestSuite) TestUnified() {
+	a := Lines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n")
+	b := Lines("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n15\n16\n")
+
+	suite.Equal(`--- a/sw1
++++ b/sw1
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -11,5 +11,5 @@
+ 11
+ 12
+ 13
+-14
+ 15
++16
+`, Unified("a/sw1", "b/sw1", a, b, DefaultContext))
+}
+
+func (suite *DiffTestSuite) TestEmptySide() {
+	suite.Equal("--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n", Unified("a", "b", nil, Lines("x\ny"), DefaultContext))
+	suite.Equal("--- a\n+++ b\n@@ -1 +0,0 @@\n-x\n", Unified("a", "b", Lines("x"), nil, DefaultContext))
+}
+
+// lcs returns the length of the longest common subsequence.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// TestEditScript checks that the script turns a into b with the minimal number of edits.
+func (suite *DiffTestSuite) TestEditScript() {
+	rnd := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rnd.Intn(20))
+		for i := range lines {
+			lines[i] = strconv.Itoa(rnd.Intn(4))
+		}
+
+		return lines
+	}
+
+	for i := 0; i < 1000; i++ {
+		a, b := randomLines(), randomLines()
+
+		var (
+			got   []string
+			edits int
+			x, y  int
+		)
+
+		for _, o := range editScript(a, b) {
+			switch o.kind {
+			case opEqual:
+				suite.Equal(a[o.a], b[o.b])
+				suite.Equal([2]int{x, y}, [2]int{o.a, o.b})
+				got = append(got, b[o.b])
+				x++
+				y++
+			case opDelete:
+				suite.Equal(x, o.a)
+				edits++
+				x++
+			case opInsert:
+				suite.Equal(y, o.b)
+				got = append(got, b[o.b])
+				edits++
+				y++
+			}
+		}
+
+		suite.Require().Equal(len(a), x, "%v %v", a, b)
+		suite.Require().Equal(strings.Join(b, ","), strings.Join(got, ","), "%v %v", a, b)
+		suite.Require().Equal(len(a)+len(b)-2*lcs(a, b), edits, "%v %v", a, b)
+	}
+}
+
+// TestLarge compares routing tables where every line changed, the memory must not grow as O(ND).
+func (suite *DiffTestSuite) TestLarge() {
+	a := make([]string, 5000)
+	b := make([]string, len(a))
+
+	for i := range a {
+		a[i] = fmt.Sprintf("O    10.%d.%d.0/24 [110/2] via 10.0.0.1, 1d%02dh, Gi0/1", i/256, i%256, i%24)
+		b[i] = fmt.Sprintf("O    10.%d.%d.0/24 [110/2] via 10.0.0.1, 2d%02dh, Gi0/1", i/256, i%256, i%24)
+	}
+
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+	out := Unified("a", "b", a, b, DefaultContext)
+	runtime.ReadMemStats(&after)
+
+	suite.Equal(2*len(a)+1, strings.Count(out, "\n")-2)
+	suite.Less(after.TotalAlloc-before.TotalAlloc, uint64(64<<20))
+}
+
+func TestDiffTestSuite(t *testing.T) {
+	suite.Run(t, new(DiffTestSuite))
+}
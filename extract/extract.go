@@ -0,0 +1,258 @@
+// Package extract gets records from command output without templates.
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jgivc/console/textfsm"
+)
+
+const defaultSeparator = ":"
+
+var (
+	ErrSpec = errors.New("invalid extract spec")
+
+	separatorLine = regexp.MustCompile(`^[\s\-=+|]+$`)
+	headerField   = regexp.MustCompile(`\S+`)
+)
+
+/*
+Spec selects one of the extraction modes:
+
+	regex      every match gives a record of the named groups
+	columns    fixed width columns named by the header line
+	key_value  "key: value" lines, a repeated key starts the next record
+*/
+type Spec struct {
+	Regex    string    `yaml:"regex"`
+	Columns  *Columns  `yaml:"columns"`
+	KeyValue *KeyValue `yaml:"key_value"`
+}
+
+type Columns struct {
+	Header string   `yaml:"header"` // Regex of the header line, the first non empty line by default
+	Names  []string `yaml:"names"`  // Record keys instead of the header fields
+	Skip   string   `yaml:"skip"`   // Regex of the lines to skip. Empty and separator lines are always skipped
+}
+
+type KeyValue struct {
+	Separator   string `yaml:"separator"`    // ":" by default
+	RecordStart string `yaml:"record_start"` // Regex of the line starting the next record
+}
+
+// Extractor is the compiled Spec.
+type Extractor struct {
+	extract func(out string) []textfsm.Record
+}
+
+// Extract returns the records of the output. Output without records gives nil.
+func (e *Extractor) Extract(out string) []textfsm.Record {
+	return e.extract(strings.ReplaceAll(out, "\r\n", "\n"))
+}
+
+func compile(name, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrSpec, name, err)
+	}
+
+	return re, nil
+}
+
+func newRegex(pattern string) (func(string) []textfsm.Record, error) {
+	re, err := compile("regex", "(?m)"+pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	names := re.SubexpNames()
+	named := false
+
+	for _, name := range names {
+		named = named || name != ""
+	}
+
+	if !named {
+		return nil, fmt.Errorf("%w: regex has no named groups", ErrSpec)
+	}
+
+	return func(out string) []textfsm.Record {
+		var records []textfsm.Record
+
+		for _, m := range re.FindAllStringSubmatch(out, -1) {
+			r := make(textfsm.Record)
+
+			for i, name := range names {
+				if name != "" {
+					r[name] = m[i]
+				}
+			}
+
+			records = append(records, r)
+		}
+
+		return records
+	}, nil
+}
+
+type column struct {
+	name  string
+	start int
+}
+
+func splitColumns(columns []column, line string) textfsm.Record {
+	r := make(textfsm.Record, len(columns))
+
+	for i, c := range columns {
+		end := len(line)
+		if i+1 < len(columns) && columns[i+1].start < end {
+			end = columns[i+1].start
+		}
+
+		value := ""
+		if c.start < end {
+			value = strings.TrimSpace(line[c.start:end])
+		}
+
+		r[c.name] = value
+	}
+
+	return r
+}
+
+func newColumns(spec *Columns) (func(string) []textfsm.Record, error) {
+	header, err := compile("columns header", spec.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	skip, err := compile("columns skip", spec.Skip)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(out string) []textfsm.Record {
+		var (
+			records []textfsm.Record
+			columns []column
+		)
+
+		for _, line := range strings.Split(out, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			if columns == nil {
+				if header != nil && !header.MatchString(line) {
+					continue
+				}
+
+				for i, loc := range headerField.FindAllStringIndex(line, -1) {
+					name := line[loc[0]:loc[1]]
+					if i < len(spec.Names) {
+						name = spec.Names[i]
+					}
+
+					columns = append(columns, column{name: name, start: loc[0]})
+				}
+
+				continue
+			}
+
+			if separatorLine.MatchString(line) || (skip != nil && skip.MatchString(line)) {
+				continue
+			}
+
+			records = append(records, splitColumns(columns, line))
+		}
+
+		return records
+	}, nil
+}
+
+func newKeyValue(spec *KeyValue) (func(string) []textfsm.Record, error) {
+	start, err := compile("key_value record_start", spec.RecordStart)
+	if err != nil {
+		return nil, err
+	}
+
+	separator := spec.Separator
+	if separator == "" {
+		separator = defaultSeparator
+	}
+
+	return func(out string) []textfsm.Record {
+		var (
+			records []textfsm.Record
+			current textfsm.Record
+		)
+
+		for _, line := range strings.Split(out, "\n") {
+			key, value, found := strings.Cut(line, separator)
+			key = strings.TrimSpace(key)
+
+			_, repeated := current[key]
+			if current != nil && ((start != nil && start.MatchString(line)) || (found && repeated)) {
+				records = append(records, current)
+				current = nil
+			}
+
+			if !found || key == "" {
+				continue
+			}
+
+			if current == nil {
+				current = make(textfsm.Record)
+			}
+
+			current[key] = strings.TrimSpace(value)
+		}
+
+		if current != nil {
+			records = append(records, current)
+		}
+
+		return records
+	}, nil
+}
+
+// New compiles the spec. Exactly one mode must be set.
+func New(spec *Spec) (*Extractor, error) {
+	var (
+		e     Extractor
+		err   error
+		modes int
+	)
+
+	if spec.Regex != "" {
+		modes++
+		e.extract, err = newRegex(spec.Regex)
+	}
+
+	if spec.Columns != nil && err == nil {
+		modes++
+		e.extract, err = newColumns(spec.Columns)
+	}
+
+	if spec.KeyValue != nil && err == nil {
+		modes++
+		e.extract, err = newKeyValue(spec.KeyValue)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if modes != 1 {
+		return nil, fmt.Errorf("%w: exactly one of regex, columns or key_value must be set", ErrSpec)
+	}
+
+	return &e, nil
+}
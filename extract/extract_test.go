@@ -0,0 +1,102 @@
+package extract
+
+import (
+	"testing"
+
+	"github.com/jgivc/console/textfsm"
+	"github.com/stretchr/testify/suite"
+)
+
+const showIPIntBrief = `sh ip int br
+Interface              IP-Address      OK? Method Status                Protocol
+GigabitEthernet0/0     10.0.0.1        YES manual up                    up
+GigabitEthernet0/1     unassigned      YES unset  administratively down down
+Vlan1                  unassigned      YES unset  down                  down
+`
+
+const showCDPNeighborsDetail = `-------------------------
+Device ID: sw2
+  IP address: 10.0.0.2
+Interface: GigabitEthernet0/1
+-------------------------
+Device ID: sw3
+Interface: GigabitEthernet0/2
+`
+
+type ExtractTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ExtractTestSuite) extract(spec *Spec, out string) []textfsm.Record {
+	e, err := New(spec)
+	suite.Require().NoError(err)
+
+	return e.Extract(out)
+}
+
+func (suite *ExtractTestSuite) TestRegex() {
+	suite.Equal([]textfsm.Record{
+		{"intf": "GigabitEthernet0/0", "ip": "10.0.0.1"},
+	}, suite.extract(&Spec{Regex: `^(?P<intf>\S+)\s+(?P<ip>\d+\.\d+\.\d+\.\d+)`}, showIPIntBrief))
+
+	suite.Nil(suite.extract(&Spec{Regex: `^(?P<intf>Loopback\d+)`}, showIPIntBrief))
+}
+
+func (suite *ExtractTestSuite) TestColumns() {
+	records := suite.extract(&Spec{Columns: &Columns{Header: `^Interface`}}, showIPIntBrief+"----\nsw1#")
+	suite.Require().Len(records, 4)
+	suite.Equal(textfsm.Record{
+		"Interface":  "GigabitEthernet0/1",
+		"IP-Address": "unassigned",
+		"OK?":        "YES",
+		"Method":     "unset",
+		"Status":     "administratively down",
+		"Protocol":   "down",
+	}, records[1])
+
+	records = suite.extract(&Spec{Columns: &Columns{
+		Header: `^Interface`,
+		Names:  []string{"intf", "ip"},
+		Skip:   `#$`,
+	}}, showIPIntBrief+"sw1#")
+	suite.Require().Len(records, 3)
+	suite.Equal("Vlan1", records[2]["intf"])
+	suite.Equal("unassigned", records[2]["ip"])
+	suite.Equal("down", records[2]["Protocol"])
+}
+
+func (suite *ExtractTestSuite) TestKeyValue() {
+	out := "Hostname: sw1\nModel : C2960\nno separator\nHostname: sw2\nModel: C3750\n"
+
+	suite.Equal([]textfsm.Record{
+		{"Hostname": "sw1", "Model": "C2960"},
+		{"Hostname": "sw2", "Model": "C3750"},
+	}, suite.extract(&Spec{KeyValue: &KeyValue{}}, out))
+
+	suite.Equal([]textfsm.Record{
+		{"Device ID": "sw2", "IP address": "10.0.0.2", "Interface": "GigabitEthernet0/1"},
+		{"Device ID": "sw3", "Interface": "GigabitEthernet0/2"},
+	}, suite.extract(&Spec{KeyValue: &KeyValue{RecordStart: `^---`}}, showCDPNeighborsDetail))
+
+	suite.Equal([]textfsm.Record{
+		{"name": "sw1", "model": "C2960"},
+	}, suite.extract(&Spec{KeyValue: &KeyValue{Separator: "="}}, "name=sw1\nmodel = C2960\n"))
+}
+
+func (suite *ExtractTestSuite) TestBadSpec() {
+	for _, spec := range []*Spec{
+		{},
+		{Regex: `(\S+)`},
+		{Regex: `(?P<a>`},
+		{Regex: `(?P<a>\S+)`, KeyValue: &KeyValue{}},
+		{Columns: &Columns{Header: `(`}},
+		{KeyValue: &KeyValue{RecordStart: `(`}},
+	} {
+		_, err := New(spec)
+		suite.ErrorIs(err, ErrSpec)
+	}
+}
+
+func TestExtractTestSuite(t *testing.T) {
+	suite.Run(t, new(ExtractTestSuite))
+}
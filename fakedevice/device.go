@@ -0,0 +1,258 @@
+package fakedevice
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+const (
+	loginAttempts  = 3
+	moreLine       = " --More-- "
+	moreErase      = "\r          \r"
+	invalidInput   = "% Invalid input detected\r\n"
+	authFailed     = "% Authentication failed\r\n"
+	accessDenied   = "% Access denied\r\n"
+	terminalLength = "terminal length 0"
+)
+
+/*
+Device emulates the CLI of a network device like Cisco IOS:
+
+	Username: / Password: login if Username is set (leave it empty for SSH, the server authenticates)
+	host> and host# prompts, enable (with EnablePassword if set) and disable, abbreviated as on IOS
+	Commands output, paged with --More-- after PageLines lines until "terminal length 0"
+	exit or quit closes the session
+
+Commands are not echoed, as with a pty without ECHO.
+*/
+type Device struct {
+	Hostname       string
+	Banner         string
+	Username       string
+	Password       string
+	EnablePassword string
+	Privileged     bool              // Start in privileged mode
+	Commands       map[string]string // Command -> output, lines are separated with \n
+	PageLines      int               // 0 - no pager
+}
+
+type deviceSession struct {
+	d          *Device
+	r          *bufio.Reader
+	w          io.Writer
+	privileged bool
+	pageLines  int
+	skipLF     bool
+}
+
+// readLine returns the next line, the line is terminated with \r, \n or \r\n.
+func (s *deviceSession) readLine() (string, error) {
+	var sb strings.Builder
+
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		if s.skipLF {
+			s.skipLF = false
+			if b == '\n' {
+				continue
+			}
+		}
+
+		if b == '\r' || b == '\n' {
+			s.skipLF = b == '\r'
+			return strings.TrimSpace(sb.String()), nil
+		}
+
+		sb.WriteByte(b)
+	}
+}
+
+func (s *deviceSession) readKey() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err == nil && s.skipLF && b == '\n' {
+		b, err = s.r.ReadByte()
+	}
+
+	s.skipLF = false
+
+	return b, err
+}
+
+func (s *deviceSession) write(data string) error {
+	_, err := io.WriteString(s.w, data)
+	return err
+}
+
+func (s *deviceSession) ask(prompt string) (string, error) {
+	if err := s.write(prompt); err != nil {
+		return "", err
+	}
+
+	return s.readLine()
+}
+
+func (s *deviceSession) login() (bool, error) {
+	if s.d.Username == "" {
+		return true, nil
+	}
+
+	for i := 0; i < loginAttempts; i++ {
+		username, err := s.ask("Username: ")
+		if err != nil {
+			return false, err
+		}
+
+		password, err := s.ask("Password: ")
+		if err != nil {
+			return false, err
+		}
+
+		if username == s.d.Username && password == s.d.Password {
+			return true, nil
+		}
+
+		if err := s.write(authFailed); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+func (s *deviceSession) prompt() string {
+	if s.privileged {
+		return s.d.Hostname + "#"
+	}
+
+	return s.d.Hostname + ">"
+}
+
+func (s *deviceSession) enable() error {
+	if s.d.EnablePassword != "" {
+		password, err := s.ask("Password: ")
+		if err != nil {
+			return err
+		}
+
+		if password != s.d.EnablePassword {
+			return s.write(accessDenied)
+		}
+	}
+
+	s.privileged = true
+
+	return nil
+}
+
+// page writes the output, waiting for a key after every pageLines lines:
+// space shows the next page, enter the next line, anything else stops the output.
+func (s *deviceSession) page(output string) error {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	lines := strings.SplitAfter(strings.ReplaceAll(output, "\n", "\r\n"), "\r\n")
+	left := s.pageLines
+
+	for _, line := range lines {
+		if s.pageLines > 0 && left == 0 && line != "" {
+			if err := s.write(moreLine); err != nil {
+				return err
+			}
+
+			b, err := s.readKey()
+			if err != nil {
+				return err
+			}
+
+			if err := s.write(moreErase); err != nil {
+				return err
+			}
+
+			switch b {
+			case ' ':
+				left = s.pageLines
+			case '\r', '\n':
+				left = 1
+			default:
+				return nil
+			}
+		}
+
+		if err := s.write(line); err != nil {
+			return err
+		}
+
+		left--
+	}
+
+	if !strings.HasSuffix(output, "\n") && output != "" {
+		return s.write("\r\n")
+	}
+
+	return nil
+}
+
+func (s *deviceSession) exec(cmd string) (bool, error) {
+	switch {
+	case cmd == "":
+		return true, nil
+	case abbrev(cmd, "exit", 2) || abbrev(cmd, "quit", 1):
+		return false, nil
+	case abbrev(cmd, "enable", 2) && !s.privileged:
+		return true, s.enable()
+	case abbrev(cmd, "disable", 4):
+		s.privileged = false
+		return true, nil
+	case cmd == terminalLength:
+		s.pageLines = 0
+		return true, nil
+	}
+
+	output, ok := s.d.Commands[cmd]
+	if !ok {
+		return true, s.write(invalidInput)
+	}
+
+	return true, s.page(output)
+}
+
+// abbrev reports whether cmd is the command or its abbreviation at least minLen characters long.
+func abbrev(cmd, command string, minLen int) bool {
+	return len(cmd) >= minLen && strings.HasPrefix(command, cmd)
+}
+
+func (d *Device) Serve(rw io.ReadWriter) error {
+	s := &deviceSession{
+		d:          d,
+		r:          bufio.NewReader(rw),
+		w:          rw,
+		privileged: d.Privileged,
+		pageLines:  d.PageLines,
+	}
+
+	if d.Banner != "" {
+		if err := s.write(d.Banner); err != nil {
+			return err
+		}
+	}
+
+	ok, err := s.login()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		cmd, err := s.ask(s.prompt())
+		if err != nil {
+			return err
+		}
+
+		more, err := s.exec(cmd)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package fakedevice
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jgivc/console/transport"
+)
+
+const scenarioPollInterval = 50 * time.Millisecond
+
+// Scenario returns Handler that plays the dummy transport scenario in every session.
+// The session is closed when the scenario ends or the client writes unexpected input.
+func Scenario(rd *transport.ReaderData) Handler {
+	return HandlerFunc(func(rw io.ReadWriter) error {
+		p := transport.NewScenarioPlayer(rd, scenarioPollInterval)
+		defer p.Close()
+
+		gone := make(chan struct{})
+
+		go func() {
+			_, _ = io.Copy(p, rw)
+			close(gone)
+		}()
+
+		b := make([]byte, 4096)
+
+		for {
+			n, err := p.Read(b)
+			if n > 0 {
+				if _, err2 := rw.Write(b[:n]); err2 != nil {
+					return err2
+				}
+			}
+
+			switch {
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				select {
+				case <-gone:
+					return io.EOF
+				default:
+				}
+			case errors.Is(err, io.EOF):
+				if p.Finished() {
+					return nil
+				}
+			case err != nil:
+				return err
+			}
+		}
+	})
+}
+
+// ScenarioFile returns Handler that plays the dummy transport scenario from the file.
+func ScenarioFile(fileName string) (Handler, error) {
+	rd, err := transport.LoadScenario(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return Scenario(rd), nil
+}
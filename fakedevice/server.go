@@ -0,0 +1,315 @@
+/*
+Package fakedevice starts in-process SSH and telnet servers on localhost, so the real transports
+can be exercised in go test. Sessions are served by a Handler: a Device emulator or a dummy transport scenario.
+*/
+package fakedevice
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jgivc/console/host"
+	"github.com/jgivc/console/transport"
+	"github.com/jgivc/console/transport/telnet"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	listenAddr          = "127.0.0.1:0"
+	sshKeepAliveRequest = "keepalive@openssh.com"
+	sshShellTimeout     = 5 * time.Second
+)
+
+var ErrAuthFailed = errors.New("authentication failed")
+
+// Handler serves one session. The session is closed when Serve returns.
+type Handler interface {
+	Serve(rw io.ReadWriter) error
+}
+
+type HandlerFunc func(rw io.ReadWriter) error
+
+func (f HandlerFunc) Serve(rw io.ReadWriter) error {
+	return f(rw)
+}
+
+type Server struct {
+	listener      net.Listener
+	handler       Handler
+	transportType int
+	serveConn     func(conn net.Conn)
+	wg            sync.WaitGroup
+	mu            sync.Mutex
+	conns         map[net.Conn]struct{}
+	closed        bool
+	keepAlives    atomic.Int64
+	errs          []error
+}
+
+// Addr returns host:port the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Host returns the host to connect to the server with the account.
+func (s *Server) Host(account host.Account) *host.Host {
+	addr := s.listener.Addr().(*net.TCPAddr)
+
+	return &host.Host{
+		Host:          addr.IP.String(),
+		Port:          addr.Port,
+		TransportType: s.transportType,
+		Account:       account,
+	}
+}
+
+// KeepAlives returns the number of SSH keepalive requests received.
+func (s *Server) KeepAlives() int {
+	return int(s.keepAlives.Load())
+}
+
+// Errors returns the errors returned by the handler, e.g. transport.ErrUnexpectedInput.
+func (s *Server) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]error(nil), s.errs...)
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+func (s *Server) serve(rw io.ReadWriter) {
+	if err := s.handler.Serve(rw); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+		s.mu.Lock()
+		s.errs = append(s.errs, err)
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+			return
+		}
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			defer conn.Close()
+
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// Close stops the server and closes all sessions.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.closed = true
+	err := s.listener.Close()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+func newServer(handler Handler, transportType int) (*Server, error) {
+	l, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen: %w", err)
+	}
+
+	return &Server{
+		listener:      l,
+		handler:       handler,
+		transportType: transportType,
+		conns:         make(map[net.Conn]struct{}),
+	}, nil
+}
+
+func (s *Server) start() *Server {
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	return s
+}
+
+// NewTelnetServer starts the telnet server. Login prompts, if any, are up to the handler.
+func NewTelnetServer(handler Handler) (*Server, error) {
+	s, err := newServer(handler, transport.TransportTELNET)
+	if err != nil {
+		return nil, err
+	}
+
+	s.serveConn = func(conn net.Conn) {
+		s.serve(telnet.NewConn(conn))
+	}
+
+	return s.start(), nil
+}
+
+/*
+NewSSHServer starts the SSH server with password authentication for the account.
+Any username and password are accepted if account.Username is empty.
+The host key is generated on every start.
+*/
+func NewSSHServer(handler Handler, account host.Account) (*Server, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate host key: %w", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create host key signer: %w", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if account.Username == "" || (conn.User() == account.Username && string(password) == account.Password) {
+				return nil, nil
+			}
+
+			return nil, ErrAuthFailed
+		},
+	}
+	config.AddHostKey(signer)
+
+	s, err := newServer(handler, transport.TransportSSH)
+	if err != nil {
+		return nil, err
+	}
+
+	s.serveConn = func(conn net.Conn) {
+		s.serveSSH(conn, config)
+	}
+
+	return s.start(), nil
+}
+
+func (s *Server) serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	defer sshConn.Close()
+
+	go s.globalRequests(reqs)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		ch, chReqs, err2 := newChannel.Accept()
+		if err2 != nil {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			s.session(ch, chReqs)
+		}()
+	}
+}
+
+func (s *Server) globalRequests(reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		if req.Type == sshKeepAliveRequest {
+			s.keepAlives.Add(1)
+		}
+
+		if req.WantReply {
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// session accepts pty and shell requests and serves the shell.
+func (s *Server) session(ch ssh.Channel, reqs <-chan *ssh.Request) {
+	defer ch.Close()
+
+	shell := make(chan struct{})
+	started := false
+
+	go func() {
+		for req := range reqs {
+			ok := false
+
+			switch req.Type {
+			case "pty-req", "env", "window-change":
+				ok = true
+			case "shell":
+				ok = !started
+				if ok {
+					started = true
+					close(shell)
+				}
+			}
+
+			if req.WantReply {
+				req.Reply(ok, nil)
+			}
+		}
+	}()
+
+	select {
+	case <-shell:
+	case <-time.After(sshShellTimeout):
+		return
+	}
+
+	s.serve(ch)
+
+	_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+}
@@ -0,0 +1,206 @@
+package fakedevice
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgivc/console"
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/host"
+	"github.com/jgivc/console/transport"
+	"github.com/stretchr/testify/suite"
+)
+
+const testScenario = "../transport/testdata/dummy_interactive.xml"
+
+type ServerTestSuite struct {
+	suite.Suite
+	cfg     *config.ConsoleConfig
+	account host.Account
+	device  *Device
+}
+
+func (suite *ServerTestSuite) SetupTest() {
+	suite.cfg = config.DefaultConsoleConfig()
+	suite.cfg.AuthTimeout = 2 * time.Second
+	suite.cfg.ExecTimeout = 2 * time.Second
+	suite.cfg.TransportReadTimeout = 50 * time.Millisecond
+
+	suite.account = host.Account{Username: "admin", Password: "secret", EnablePassword: "enable"}
+	suite.device = &Device{
+		Hostname:       "sw1",
+		Banner:         "\r\nUser Access Verification\r\n\r\n",
+		EnablePassword: suite.account.EnablePassword,
+		Commands: map[string]string{
+			"show version": "Version 15.2",
+		},
+	}
+}
+
+func (suite *ServerTestSuite) open(srv *Server, account host.Account) (console.Console, error) {
+	c := console.NewWithConfig(suite.cfg)
+	err := c.Open(context.Background(), srv.Host(account))
+
+	return c, err
+}
+
+func (suite *ServerTestSuite) execute(c console.Console, cmd string) string {
+	out, err := c.Execute(cmd)
+	suite.Require().NoError(err)
+
+	return out
+}
+
+func (suite *ServerTestSuite) TestSSHDevice() {
+	srv, err := NewSSHServer(suite.device, suite.account)
+	suite.Require().NoError(err)
+	defer srv.Close()
+
+	c, err := suite.open(srv, suite.account)
+	suite.Require().NoError(err)
+	defer c.Close()
+
+	suite.True(c.Privileged())
+	suite.Contains(suite.execute(c, "show version"), "Version 15.2")
+	suite.Contains(suite.execute(c, "show clock"), "% Invalid input")
+}
+
+func (suite *ServerTestSuite) TestSSHAuthFailed() {
+	srv, err := NewSSHServer(suite.device, suite.account)
+	suite.Require().NoError(err)
+	defer srv.Close()
+
+	account := suite.account
+	account.Password = "wrong"
+
+	_, err = suite.open(srv, account)
+	suite.ErrorIs(err, console.ErrAuthFailed)
+}
+
+func (suite *ServerTestSuite) TestTelnetAuthFailed() {
+	suite.device.Username = suite.account.Username
+	suite.device.Password = suite.account.Password
+
+	srv, err := NewTelnetServer(suite.device)
+	suite.Require().NoError(err)
+	defer srv.Close()
+
+	account := suite.account
+	account.Password = "wrong"
+
+	_, err = suite.open(srv, account)
+	suite.ErrorIs(err, console.ErrAuthFailed)
+}
+
+func (suite *ServerTestSuite) TestSSHKeepAlive() {
+	srv, err := NewSSHServer(suite.device, suite.account)
+	suite.Require().NoError(err)
+	defer srv.Close()
+
+	suite.cfg.KeepAliveInterval = 20 * time.Millisecond
+
+	c, err := suite.open(srv, suite.account)
+	suite.Require().NoError(err)
+	defer c.Close()
+
+	suite.Eventually(func() bool { return srv.KeepAlives() > 0 }, time.Second, 10*time.Millisecond)
+}
+
+func (suite *ServerTestSuite) TestTelnetDevice() {
+	suite.device.Username = suite.account.Username
+	suite.device.Password = suite.account.Password
+
+	srv, err := NewTelnetServer(suite.device)
+	suite.Require().NoError(err)
+	defer srv.Close()
+
+	c, err := suite.open(srv, suite.account)
+	suite.Require().NoError(err)
+	defer c.Close()
+
+	suite.True(c.Privileged())
+	suite.Contains(suite.execute(c, "show version"), "Version 15.2")
+
+	suite.Require().NoError(c.Disable())
+	suite.False(c.Privileged())
+}
+
+func (suite *ServerTestSuite) TestTelnetScenario() {
+	h, err := ScenarioFile(testScenario)
+	suite.Require().NoError(err)
+
+	srv, err := NewTelnetServer(h)
+	suite.Require().NoError(err)
+	defer srv.Close()
+
+	c, err := suite.open(srv, host.Account{Username: "admin", Password: "secret"})
+	suite.Require().NoError(err)
+	defer c.Close()
+
+	suite.Contains(suite.execute(c, "sh ver"), "Version 15.2")
+	suite.Empty(srv.Errors())
+}
+
+func (suite *ServerTestSuite) TestScenarioUnexpectedInput() {
+	h, err := ScenarioFile(testScenario)
+	suite.Require().NoError(err)
+
+	srv, err := NewTelnetServer(h)
+	suite.Require().NoError(err)
+	defer srv.Close()
+
+	_, err = suite.open(srv, host.Account{Username: "root", Password: "secret"})
+	suite.Error(err)
+
+	suite.Eventually(func() bool { return len(srv.Errors()) > 0 }, time.Second, 10*time.Millisecond)
+	suite.ErrorIs(srv.Errors()[0], transport.ErrUnexpectedInput)
+}
+
+func (suite *ServerTestSuite) TestPager() {
+	suite.device.Privileged = true
+	suite.device.PageLines = 2
+	suite.device.Commands["show run"] = "line 1\nline 2\nline 3\nline 4\nline 5"
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go suite.device.Serve(server) //nolint:errcheck
+
+	r := bufio.NewReader(client)
+	readUntil := func(s string) string {
+		var sb strings.Builder
+		for !strings.HasSuffix(sb.String(), s) {
+			b, err := r.ReadByte()
+			suite.Require().NoError(err)
+			sb.WriteByte(b)
+		}
+
+		return sb.String()
+	}
+
+	readUntil("sw1#")
+	_, err := client.Write([]byte("show run\r"))
+	suite.Require().NoError(err)
+	suite.Equal("line 1\r\nline 2\r\n --More-- ", readUntil("--More-- "))
+
+	_, err = client.Write([]byte(" "))
+	suite.Require().NoError(err)
+	suite.Contains(readUntil("--More-- "), "line 3\r\nline 4\r\n")
+
+	_, err = client.Write([]byte("q"))
+	suite.Require().NoError(err)
+	suite.NotContains(readUntil("sw1#"), "line 5")
+
+	_, err = client.Write([]byte("terminal length 0\rshow run\r"))
+	suite.Require().NoError(err)
+	readUntil("sw1#")
+	suite.Contains(readUntil("sw1#"), "line 5")
+}
+
+func TestServerTestSuite(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
@@ -22,3 +22,21 @@ func (h *Host) GetHostPort() string {
 func (h *Host) HasAccount() bool {
 	return !(h.Account == (Account{}))
 }
+
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "***"
+}
+
+// String returns the account with masked passwords, so it can be logged.
+func (a Account) String() string {
+	return fmt.Sprintf("{%s %s %s}", a.Username, mask(a.Password), mask(a.EnablePassword))
+}
+
+// String hides the promoted Account.String and masks the passwords too.
+func (h Host) String() string {
+	return fmt.Sprintf("{%s %d %d %s}", h.Host, h.Port, h.TransportType, h.Account)
+}
@@ -0,0 +1,96 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jgivc/console/transport"
+)
+
+// keepAlive keeps an idle session from being disconnected by device exec-timeout or ssh idle timers.
+// It stops on the first error, which is then added to the errors of the next commands.
+type keepAlive struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (k *keepAlive) run(c *console, interval time.Duration) {
+	defer close(k.done)
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-k.stop:
+			return
+		case <-timer.C:
+		}
+
+		next, err := c.keepAliveIfIdle(interval)
+		if err != nil {
+			c.mu.Lock()
+			c.keepAliveErr = err
+			c.mu.Unlock()
+
+			return
+		}
+
+		timer.Reset(next)
+	}
+}
+
+func (k *keepAlive) Stop() {
+	close(k.stop)
+	<-k.done
+}
+
+func startKeepAlive(c *console, interval time.Duration) *keepAlive {
+	k := &keepAlive{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go k.run(c, interval)
+
+	return k
+}
+
+// keepAliveIfIdle sends keepalive if nothing was sent or received during the interval.
+// It returns the time to wait before the next check.
+func (c *console) keepAliveIfIdle(interval time.Duration) (time.Duration, error) {
+	if idle := c.idle(); idle < interval {
+		return interval - idle, nil
+	}
+
+	q := c.queue()
+	if q == nil {
+		return 0, fmt.Errorf("%w: console is not open", ErrConnectionLost)
+	}
+
+	// Skip if a command is running, it keeps the session alive by itself.
+	if _, err := q.tryDo(&job{ctx: context.Background(), f: c.sendKeepAlive}); err != nil {
+		return 0, err
+	}
+
+	return interval, nil
+}
+
+// sendKeepAlive uses the transport keepalive, or runs KeepAliveCommand if it is set
+// or the transport has no keepalive of its own.
+func (c *console) sendKeepAlive() error {
+	if c.cfg.KeepAliveCommand == "" {
+		if ka, ok := c.transport.(transport.KeepAliver); ok {
+			if err := ka.KeepAlive(); !errors.Is(err, transport.ErrNotSupported) {
+				return err
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.ExecTimeout)
+	defer cancel()
+
+	return c.run(ctx, c.cfg.KeepAliveCommand)
+}
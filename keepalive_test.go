@@ -0,0 +1,111 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/jgivc/console/host"
+	"github.com/jgivc/console/util"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockKeepAliveTransport struct {
+	MockTransport
+}
+
+func (m *MockKeepAliveTransport) KeepAlive() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (suite *ConsoleTestSuite) TestKeepAlive() {
+	tr := new(MockKeepAliveTransport)
+	suite.transport = &tr.MockTransport
+	suite.console.cfg.KeepAliveInterval = 20 * time.Millisecond
+
+	var written []string
+
+	tr.On("Open", mock.Anything, mock.Anything).Return(nil)
+	suite.onRead("Password: ")
+	suite.onRead("sw1#")
+	tr.On("Read", mock.Anything).Return(0, os.ErrDeadlineExceeded).Maybe()
+	suite.onWrite(&written)
+	tr.On("KeepAlive").Return(nil)
+	tr.On("Close").Return(nil)
+
+	suite.factory.On("GetTransport", mock.Anything).Return(tr, nil)
+	err := suite.console.Open(context.Background(), &host.Host{
+		Account: host.Account{Password: "pass"},
+	})
+	suite.Require().NoError(err)
+
+	time.Sleep(100 * time.Millisecond)
+	suite.Require().NoError(suite.console.Close())
+
+	tr.AssertCalled(suite.T(), "KeepAlive")
+	suite.Equal([]string{"pass"}, written)
+}
+
+func (suite *ConsoleTestSuite) TestKeepAliveCommand() {
+	suite.console.cfg.KeepAliveInterval = 50 * time.Millisecond
+	suite.console.cfg.KeepAliveCommand = "show clock"
+
+	var written []string
+
+	suite.transport.On("Open", mock.Anything, mock.Anything).Return(nil)
+	suite.onRead("Password: ")
+	suite.onRead("sw1#")
+	suite.onRead("sw1#show clock\n10:58:35.049 UTC Tue Apr 5 2022\nsw1#")
+	suite.transport.On("Read", mock.Anything).Return(0, os.ErrDeadlineExceeded).Maybe()
+	suite.onWrite(&written)
+	suite.transport.On("Close").Return(nil)
+
+	suite.factory.On("GetTransport", mock.Anything).Return(suite.transport, nil)
+	err := suite.console.Open(context.Background(), &host.Host{
+		Account: host.Account{Password: "pass"},
+	})
+	suite.Require().NoError(err)
+
+	time.Sleep(70 * time.Millisecond)
+	suite.Require().NoError(suite.console.Close())
+
+	suite.Equal([]string{"pass", "show clock"}, written)
+}
+
+func (suite *ConsoleTestSuite) TestKeepAliveFailed() {
+	tr := new(MockKeepAliveTransport)
+	suite.transport = &tr.MockTransport
+	suite.console.cfg.KeepAliveInterval = 20 * time.Millisecond
+	suite.console.cfg.ExecTimeout = 20 * time.Millisecond
+
+	var written []string
+
+	errKeepAlive := errors.New("keepalive request failed")
+
+	tr.On("Open", mock.Anything, mock.Anything).Return(nil)
+	suite.onRead("Password: ")
+	suite.onRead("sw1#")
+	tr.On("Read", mock.Anything).Return(0, os.ErrDeadlineExceeded).Maybe()
+	suite.onWrite(&written)
+	tr.On("KeepAlive").Return(errKeepAlive).Once()
+	tr.On("Close").Return(nil)
+
+	suite.factory.On("GetTransport", mock.Anything).Return(tr, nil)
+	err := suite.console.Open(context.Background(), &host.Host{
+		Account: host.Account{Password: "pass"},
+	})
+	suite.Require().NoError(err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The session timed out as nothing kept it alive, the error tells why.
+	_, err = suite.console.Execute("sh ver")
+	suite.ErrorIs(err, util.ErrNoPromptFound)
+	suite.ErrorIs(err, errKeepAlive)
+	suite.ErrorContains(err, "keepalive stopped")
+
+	suite.Require().NoError(suite.console.Close())
+	tr.AssertNumberOfCalls(suite.T(), "KeepAlive", 1)
+}
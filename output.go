@@ -0,0 +1,22 @@
+package console
+
+import "strings"
+
+/*
+CommandOutput returns the output of Execute without the echoed command line
+and the prompt line after the output. The first line is kept if it is not the command echo.
+*/
+func CommandOutput(cmd, out string) string {
+	i := strings.LastIndexAny(out, "\r\n")
+	if i < 0 {
+		return ""
+	}
+
+	out = out[:i+1]
+
+	if i = strings.IndexByte(out, '\n'); i >= 0 && strings.HasSuffix(strings.TrimSpace(out[:i]), strings.TrimSpace(cmd)) {
+		out = out[i+1:]
+	}
+
+	return out
+}
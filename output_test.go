@@ -0,0 +1,29 @@
+package console
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OutputTestSuite struct {
+	suite.Suite
+}
+
+func (suite *OutputTestSuite) TestCommandOutput() {
+	for _, tc := range []struct {
+		out, expected string
+	}{
+		{"sh clock\r\n10:00:00 UTC\r\nsw1#", "10:00:00 UTC\r\n"},
+		{"sw1#sh clock\n10:00:00 UTC\nsw1#", "10:00:00 UTC\n"},
+		{"10:00:00 UTC\nsw1#", "10:00:00 UTC\n"},
+		{"sh clock\r\nsw1#", ""},
+		{"sw1#", ""},
+	} {
+		suite.Equal(tc.expected, CommandOutput("sh clock", tc.out), tc.out)
+	}
+}
+
+func TestOutputTestSuite(t *testing.T) {
+	suite.Run(t, new(OutputTestSuite))
+}
@@ -0,0 +1,333 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jgivc/console"
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/host"
+)
+
+const (
+	defaultMaxIdle            = 2
+	defaultMaxLifetime        = 30 * time.Minute
+	defaultIdleTimeout        = 5 * time.Minute
+	defaultHealthCheckTimeout = 5 * time.Second
+)
+
+var ErrPoolClosed = errors.New("pool is closed")
+
+type Config struct {
+	MaxIdle            int           // Max idle consoles per key, 0 - default, negative - none are kept
+	MaxLifetime        time.Duration // Consoles older than that are closed instead of reuse, 0 - no limit
+	IdleTimeout        time.Duration // Idle consoles unused that long are closed, 0 - no limit
+	HealthCheckCommand string        // No-op command to check idle console before borrow, empty line by default
+	HealthCheckTimeout time.Duration
+	InitialCommands    []string // Commands to run on every new console
+	ConsoleConfig      *config.ConsoleConfig
+	NewConsole         func(h *host.Host) console.Console // Defaults to console.NewWithConfig(ConsoleConfig)
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		MaxIdle:            defaultMaxIdle,
+		MaxLifetime:        defaultMaxLifetime,
+		IdleTimeout:        defaultIdleTimeout,
+		HealthCheckTimeout: defaultHealthCheckTimeout,
+		ConsoleConfig:      config.DefaultConsoleConfig(),
+	}
+}
+
+// Key identifies the consoles that can be shared between borrowers.
+type Key struct {
+	Host          string
+	Port          int
+	TransportType int
+	Account       host.Account
+}
+
+func KeyOf(h *host.Host) Key {
+	return Key{
+		Host:          h.Host,
+		Port:          h.Port,
+		TransportType: h.TransportType,
+		Account:       h.Account,
+	}
+}
+
+// Conn is the console borrowed from the pool. Give it back with Pool.Return or Pool.Discard.
+type Conn struct {
+	console.Console
+	key      Key
+	created  time.Time
+	returned time.Time
+}
+
+/*
+Pool keeps authenticated consoles per host for long-running services.
+Idle consoles are checked with HealthCheckCommand before they are borrowed again.
+*/
+type Pool struct {
+	cfg    *Config
+	mu     sync.Mutex
+	idle   map[Key][]*Conn
+	closed bool
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+func (p *Pool) tooOld(c *Conn, now time.Time) bool {
+	return p.cfg.MaxLifetime > 0 && now.Sub(c.created) > p.cfg.MaxLifetime
+}
+
+func (p *Pool) expired(c *Conn, now time.Time) bool {
+	return p.tooOld(c, now) || (p.cfg.IdleTimeout > 0 && now.Sub(c.returned) > p.cfg.IdleTimeout)
+}
+
+// popIdle returns the most recently returned idle console for the key.
+func (p *Pool) popIdle(key Key) (*Conn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+
+	conns := p.idle[key]
+	if len(conns) == 0 {
+		return nil, nil
+	}
+
+	c := conns[len(conns)-1]
+	p.idle[key] = conns[:len(conns)-1]
+
+	return c, nil
+}
+
+func (p *Pool) healthCheck(ctx context.Context, c *Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.HealthCheckTimeout)
+	defer cancel()
+
+	return c.RunContext(ctx, p.cfg.HealthCheckCommand)
+}
+
+func (p *Pool) open(ctx context.Context, h *host.Host) (*Conn, error) {
+	c := p.cfg.NewConsole(h)
+	if err := c.Open(ctx, h); err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range p.cfg.InitialCommands {
+		if err := c.RunContext(ctx, cmd); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("cannot run initial command %q: %w", cmd, err)
+		}
+	}
+
+	return &Conn{
+		Console: c,
+		key:     KeyOf(h),
+		created: time.Now(),
+	}, nil
+}
+
+// Borrow returns a healthy idle console for the host or opens a new one.
+func (p *Pool) Borrow(ctx context.Context, h *host.Host) (*Conn, error) {
+	key := KeyOf(h)
+
+	for {
+		c, err := p.popIdle(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if c == nil {
+			return p.open(ctx, h)
+		}
+
+		if p.expired(c, time.Now()) {
+			c.Close()
+			continue
+		}
+
+		if err := p.healthCheck(ctx, c); err != nil {
+			c.Close()
+			continue
+		}
+
+		return c, nil
+	}
+}
+
+// reset brings the console to the state of the new one, so the next borrower
+// does not depend on the prompt or privilege level left by the previous one.
+func (p *Pool) reset(c *Conn) error {
+	if err := c.Enable(); err != nil {
+		return err
+	}
+
+	return c.SetPrompt(p.cfg.ConsoleConfig.PromptPattern)
+}
+
+// Return gives the console back to the pool. The console is closed if it is broken,
+// expired or there are already MaxIdle idle consoles for the host.
+func (p *Pool) Return(c *Conn) {
+	now := time.Now()
+	if p.tooOld(c, now) {
+		c.Close()
+		return
+	}
+
+	if err := p.reset(c); err != nil {
+		c.Close()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(p.idle[c.key]) >= p.cfg.MaxIdle {
+		c.Close()
+		return
+	}
+
+	c.returned = now
+	p.idle[c.key] = append(p.idle[c.key], c)
+}
+
+// Discard closes the console instead of returning it to the pool.
+func (p *Pool) Discard(c *Conn) error {
+	return c.Close()
+}
+
+// Len returns the number of idle consoles.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for _, conns := range p.idle {
+		n += len(conns)
+	}
+
+	return n
+}
+
+// cleanup closes expired idle consoles.
+func (p *Pool) cleanup() {
+	var expired []*Conn
+
+	now := time.Now()
+
+	p.mu.Lock()
+	for key, conns := range p.idle {
+		alive := conns[:0]
+		for _, c := range conns {
+			if p.expired(c, now) {
+				expired = append(expired, c)
+			} else {
+				alive = append(alive, c)
+			}
+		}
+
+		if len(alive) == 0 {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = alive
+		}
+	}
+	p.mu.Unlock()
+
+	for _, c := range expired {
+		c.Close()
+	}
+}
+
+func (p *Pool) cleanupLoop(interval time.Duration) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+			p.cleanup()
+		}
+	}
+}
+
+// Close closes all idle consoles. Borrowed consoles are closed when returned.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	close(p.quit)
+	<-p.done
+
+	var errs []error
+	for _, conns := range idle {
+		for _, c := range conns {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func New(cfg *Config) *Pool {
+	if cfg.ConsoleConfig == nil {
+		cfg.ConsoleConfig = config.DefaultConsoleConfig()
+	}
+
+	if cfg.MaxIdle == 0 {
+		cfg.MaxIdle = defaultMaxIdle
+	}
+
+	if cfg.HealthCheckTimeout <= 0 {
+		cfg.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+
+	if cfg.NewConsole == nil {
+		consoleConfig := cfg.ConsoleConfig
+		cfg.NewConsole = func(h *host.Host) console.Console {
+			return console.NewWithConfig(consoleConfig)
+		}
+	}
+
+	p := &Pool{
+		cfg:  cfg,
+		idle: make(map[Key][]*Conn),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	interval := cfg.IdleTimeout
+	if interval <= 0 || (cfg.MaxLifetime > 0 && cfg.MaxLifetime < interval) {
+		interval = cfg.MaxLifetime
+	}
+
+	if interval > 0 {
+		go p.cleanupLoop(interval)
+	} else {
+		close(p.done)
+	}
+
+	return p
+}
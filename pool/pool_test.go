@@ -0,0 +1,162 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jgivc/console"
+	"github.com/jgivc/console/host"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeConsole struct {
+	console.Console
+	opened  int
+	closed  bool
+	healthy bool
+	prompt  string
+	enabled int
+}
+
+func (c *fakeConsole) Open(ctx context.Context, host *host.Host) error {
+	c.opened++
+	return nil
+}
+
+func (c *fakeConsole) RunContext(ctx context.Context, cmd string) error {
+	if !c.healthy {
+		return errors.New("no prompt found")
+	}
+
+	return nil
+}
+
+func (c *fakeConsole) Enable() error {
+	c.enabled++
+	return nil
+}
+
+func (c *fakeConsole) SetPrompt(pattern string) error {
+	c.prompt = pattern
+	return nil
+}
+
+func (c *fakeConsole) Close() error {
+	c.closed = true
+	return nil
+}
+
+type PoolTestSuite struct {
+	suite.Suite
+	cfg      *Config
+	consoles []*fakeConsole
+	pool     *Pool
+	host     *host.Host
+}
+
+func (suite *PoolTestSuite) SetupTest() {
+	suite.consoles = nil
+	suite.cfg = DefaultConfig()
+	suite.cfg.MaxIdle = 1
+	suite.cfg.NewConsole = func(h *host.Host) console.Console {
+		c := &fakeConsole{healthy: true}
+		suite.consoles = append(suite.consoles, c)
+
+		return c
+	}
+	suite.host = &host.Host{Host: "10.0.0.1", Port: 22, Account: host.Account{Username: "admin"}}
+	suite.pool = New(suite.cfg)
+}
+
+func (suite *PoolTestSuite) TearDownTest() {
+	suite.NoError(suite.pool.Close())
+}
+
+func (suite *PoolTestSuite) TestReuse() {
+	c1, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+	c1.SetPrompt("custom>")
+	suite.pool.Return(c1)
+	suite.Equal(1, suite.pool.Len())
+
+	c2, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+	suite.Same(c1, c2)
+	suite.Len(suite.consoles, 1)
+	suite.Equal(suite.cfg.ConsoleConfig.PromptPattern, suite.consoles[0].prompt)
+	suite.Equal(1, suite.consoles[0].enabled)
+}
+
+func (suite *PoolTestSuite) TestKey() {
+	c1, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+	suite.pool.Return(c1)
+
+	other := *suite.host
+	other.Username = "operator"
+	c2, err := suite.pool.Borrow(context.Background(), &other)
+	suite.Require().NoError(err)
+	suite.NotSame(c1, c2)
+	suite.Len(suite.consoles, 2)
+}
+
+func (suite *PoolTestSuite) TestMaxIdle() {
+	c1, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+	c2, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+
+	suite.pool.Return(c1)
+	suite.pool.Return(c2)
+	suite.Equal(1, suite.pool.Len())
+	suite.False(suite.consoles[0].closed)
+	suite.True(suite.consoles[1].closed)
+}
+
+func (suite *PoolTestSuite) TestDefaultMaxIdle() {
+	p := New(&Config{NewConsole: suite.cfg.NewConsole})
+	defer p.Close()
+
+	c1, err := p.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+	p.Return(c1)
+	suite.Equal(1, p.Len())
+	suite.Equal(defaultMaxIdle, p.cfg.MaxIdle)
+}
+
+func (suite *PoolTestSuite) TestHealthCheckFailed() {
+	c1, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+	suite.pool.Return(c1)
+	suite.consoles[0].healthy = false
+
+	c2, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+	suite.NotSame(c1, c2)
+	suite.True(suite.consoles[0].closed)
+}
+
+func (suite *PoolTestSuite) TestMaxLifetime() {
+	suite.cfg.MaxLifetime = time.Millisecond
+
+	c1, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.Require().NoError(err)
+	time.Sleep(2 * time.Millisecond)
+	suite.pool.Return(c1)
+
+	suite.Equal(0, suite.pool.Len())
+	suite.True(suite.consoles[0].closed)
+}
+
+func (suite *PoolTestSuite) TestClosed() {
+	suite.Require().NoError(suite.pool.Close())
+
+	_, err := suite.pool.Borrow(context.Background(), suite.host)
+	suite.ErrorIs(err, ErrPoolClosed)
+}
+
+func TestPoolTestSuite(t *testing.T) {
+	suite.Run(t, new(PoolTestSuite))
+}
@@ -0,0 +1,110 @@
+package console
+
+import (
+	"context"
+	"fmt"
+)
+
+// job is the unit of work of the console queue.
+type job struct {
+	ctx  context.Context
+	f    func() error
+	hold chan struct{} // If set, the queue waits for it to be closed before the next job
+	done chan error
+}
+
+// queue executes jobs one at a time in its own goroutine.
+type queue struct {
+	jobs    chan *job
+	quit    chan struct{}
+	stopped chan struct{}
+	onDone  func()
+}
+
+func (q *queue) run() {
+	defer close(q.stopped)
+
+	for {
+		select {
+		case <-q.quit:
+			return
+		case j := <-q.jobs:
+			q.exec(j)
+		}
+	}
+}
+
+func (q *queue) exec(j *job) {
+	if err := j.ctx.Err(); err != nil {
+		j.done <- err
+		return
+	}
+
+	err := j.f()
+	q.onDone()
+	j.done <- err
+
+	if err == nil && j.hold != nil {
+		select {
+		case <-j.hold:
+		case <-q.quit:
+		}
+		q.onDone()
+	}
+}
+
+/*
+do puts j to the queue and waits for its result or ctx cancellation.
+If ctx is canceled while j is running, do returns ctx.Err() and j still runs to the end,
+so the data written by j can be read only if do returned nil.
+*/
+func (q *queue) do(ctx context.Context, j *job) error {
+	j.done = make(chan error, 1)
+
+	select {
+	case q.jobs <- j:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.quit:
+		return fmt.Errorf("%w: console is closed", ErrConnectionLost)
+	}
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tryDo runs j only if the queue is idle. It reports whether j was run.
+func (q *queue) tryDo(j *job) (bool, error) {
+	j.done = make(chan error, 1)
+
+	select {
+	case q.jobs <- j:
+	default:
+		return false, nil
+	}
+
+	return true, <-j.done
+}
+
+// stop waits for the running job and stops the queue.
+func (q *queue) stop() {
+	close(q.quit)
+	<-q.stopped
+}
+
+func newQueue(onDone func()) *queue {
+	q := &queue{
+		jobs:    make(chan *job),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		onDone:  onDone,
+	}
+
+	go q.run()
+
+	return q
+}
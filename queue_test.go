@@ -0,0 +1,171 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/host"
+	"github.com/jgivc/console/transport"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// echoTransport answers every command with "<cmd> output" and the prompt after a delay.
+type echoTransport struct {
+	mu    sync.Mutex
+	line  strings.Builder
+	out   chan []byte
+	delay time.Duration
+}
+
+func (t *echoTransport) Open(ctx context.Context, host *host.Host) error {
+	t.out <- []byte("sw1#")
+	return nil
+}
+
+func (t *echoTransport) SetReadTimeout(d time.Duration) {}
+
+func (t *echoTransport) Read(p []byte) (int, error) {
+	select {
+	case data := <-t.out:
+		return copy(p, data), nil
+	case <-time.After(10 * time.Millisecond):
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (t *echoTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if string(p) != string(cmdEnd) {
+		t.line.Write(p)
+		return len(p), nil
+	}
+
+	cmd := t.line.String()
+	t.line.Reset()
+
+	go func() {
+		time.Sleep(t.delay)
+		t.out <- []byte(fmt.Sprintf("%s\n%s output\nsw1#", cmd, cmd))
+	}()
+
+	return len(p), nil
+}
+
+func (t *echoTransport) Close() error {
+	return nil
+}
+
+type QueueTestSuite struct {
+	suite.Suite
+	transport *echoTransport
+	console   Console
+}
+
+func (suite *QueueTestSuite) SetupTest() {
+	suite.transport = &echoTransport{
+		out:   make(chan []byte, 1),
+		delay: 5 * time.Millisecond,
+	}
+
+	factory := new(MockTransportFactory)
+	factory.On("GetTransport", mock.Anything).Return(transport.Transport(suite.transport), nil)
+
+	suite.console = &console{
+		cfg:     config.DefaultConsoleConfig(),
+		factory: factory,
+	}
+	suite.Require().NoError(suite.console.Open(context.Background(), &host.Host{}))
+}
+
+func (suite *QueueTestSuite) TearDownTest() {
+	suite.NoError(suite.console.Close())
+}
+
+func (suite *QueueTestSuite) TestConcurrentExecute() {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			cmd := fmt.Sprintf("cmd%d", i)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			out, err := suite.console.ExecuteContext(ctx, cmd)
+			suite.NoError(err)
+			suite.Equal(fmt.Sprintf("%s\n%s output\nsw1#", cmd, cmd), out)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func (suite *QueueTestSuite) TestContextCanceledInQueue() {
+	r, err := suite.console.GetCommandResultReader("long")
+	suite.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// The reader holds the console, so the command waits in the queue.
+	_, err = suite.console.ExecuteContext(ctx, "sh ver")
+	suite.ErrorIs(err, context.DeadlineExceeded)
+
+	var out bytes.Buffer
+	_, err = out.ReadFrom(r)
+	suite.NoError(err)
+	suite.Contains(out.String(), "long output")
+
+	out2, err := suite.console.Execute("sh ver")
+	suite.NoError(err)
+	suite.Contains(out2, "sh ver output")
+}
+
+// TestCanceledWhileRunning must pass with -race: the canceled job still writes its result.
+func (suite *QueueTestSuite) TestCanceledWhileRunning() {
+	suite.transport.delay = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	out, err := suite.console.ExecuteContext(ctx, "long")
+	suite.ErrorIs(err, context.Canceled)
+	suite.Empty(out)
+
+	// Waits for the canceled job in the queue.
+	out, err = suite.console.Execute("sh ver")
+	suite.NoError(err)
+	suite.Contains(out, "sh ver output")
+}
+
+func (suite *QueueTestSuite) TestExecTimeout() {
+	suite.console.(*console).cfg.ExecTimeout = 20 * time.Millisecond
+	suite.transport.delay = 50 * time.Millisecond
+
+	// Without ctx deadline ExecTimeout is applied, not the deadline left by the login.
+	_, err := suite.console.Execute("long")
+	suite.ErrorContains(err, "no prompt found")
+}
+
+func (suite *QueueTestSuite) TestClosed() {
+	suite.Require().NoError(suite.console.Close())
+
+	_, err := suite.console.Execute("sh ver")
+	suite.ErrorIs(err, ErrConnectionLost)
+}
+
+func TestQueueTestSuite(t *testing.T) {
+	suite.Run(t, new(QueueTestSuite))
+}
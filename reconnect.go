@@ -0,0 +1,281 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jgivc/console/config"
+	"github.com/jgivc/console/host"
+)
+
+// ReconnectEvent describes one reconnect attempt.
+type ReconnectEvent struct {
+	Host    *host.Host
+	Attempt int
+	Cause   error // Error that caused the reconnect
+	Err     error // Reconnect result, nil if the session was restored
+}
+
+type ReconnectOptions struct {
+	InitialCommands       []string // Commands to run after every (re)connect
+	StrictInitialCommands bool     // Fail Open and reconnect if an initial command fails
+	Attempts              int      // Reconnect attempts per failed command
+	Backoff               time.Duration
+	MaxBackoff            time.Duration
+	Retryable             func(err error) bool        // Defaults to IsConnectionError
+	OnReconnect           func(ev ReconnectEvent)     // Called after every reconnect attempt
+	OnInitialCommandError func(cmd string, err error) // Called for the ignored initial command errors
+}
+
+// ReconnectOptionsFromConfig returns ReconnectOptions with attempts and backoff taken from cfg.
+func ReconnectOptionsFromConfig(cfg *config.ConsoleConfig) ReconnectOptions {
+	return ReconnectOptions{
+		Attempts:   cfg.ReconnectAttempts,
+		Backoff:    cfg.ReconnectBackoff,
+		MaxBackoff: cfg.ReconnectMaxBackoff,
+	}
+}
+
+// IsConnectionError reports whether err was caused by a broken transport.
+func IsConnectionError(err error) bool {
+	return errors.Is(err, ErrConnectionLost)
+}
+
+/*
+reconnectConsole wraps the Console. When a command fails with a transport level error,
+the session is reopened, authenticated, the initial commands are executed again
+and the failed command is retried.
+*/
+type reconnectConsole struct {
+	Console
+	mu         sync.Mutex // Serializes commands, so only one of them can reconnect the session
+	opts       ReconnectOptions
+	ctx        context.Context
+	host       *host.Host
+	prompt     string
+	privileged bool
+}
+
+func (c *reconnectConsole) connect() error {
+	if err := c.Console.Open(c.ctx, c.host); err != nil {
+		return err
+	}
+
+	// A rejected initial command, e.g. term le 0, does not fail the session unless strict, a broken one does.
+	for _, cmd := range c.opts.InitialCommands {
+		err := c.Console.Run(cmd)
+		if err == nil {
+			continue
+		}
+
+		if c.opts.StrictInitialCommands || c.opts.Retryable(err) {
+			return fmt.Errorf("cannot run initial command %q: %w", cmd, err)
+		}
+
+		if c.opts.OnInitialCommandError != nil {
+			c.opts.OnInitialCommandError(cmd, err)
+		}
+	}
+
+	return nil
+}
+
+// restore brings the new session to the state of the broken one.
+func (c *reconnectConsole) restore() error {
+	if c.privileged {
+		if err := c.Console.Enable(); err != nil {
+			return err
+		}
+	} else if err := c.Console.Disable(); err != nil {
+		return err
+	}
+
+	if c.prompt != "" {
+		return c.Console.SetPrompt(c.prompt)
+	}
+
+	return nil
+}
+
+func (c *reconnectConsole) backoff(attempt int) time.Duration {
+	d := c.opts.Backoff
+	for i := 1; i < attempt && d < c.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	if c.opts.MaxBackoff > 0 && d > c.opts.MaxBackoff {
+		d = c.opts.MaxBackoff
+	}
+
+	return d
+}
+
+func (c *reconnectConsole) reconnect(attempt int, cause error) error {
+	select {
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	case <-time.After(c.backoff(attempt)):
+	}
+
+	c.Console.Close()
+
+	err := c.connect()
+	if err == nil {
+		err = c.restore()
+	}
+
+	if c.opts.OnReconnect != nil {
+		c.opts.OnReconnect(ReconnectEvent{
+			Host:    c.host,
+			Attempt: attempt,
+			Cause:   cause,
+			Err:     err,
+		})
+	}
+
+	return err
+}
+
+// do runs f, reconnecting and retrying it while it fails with a retryable error.
+func (c *reconnectConsole) do(f func() error) error {
+	c.mu.Lock()
+	defer func() {
+		c.privileged = c.Console.Privileged()
+		c.mu.Unlock()
+	}()
+
+	err := f()
+	if err == nil || !c.opts.Retryable(err) {
+		return err
+	}
+
+	for attempt := 1; attempt <= c.opts.Attempts; attempt++ {
+		if errReconnect := c.reconnect(attempt, err); errReconnect != nil {
+			if c.ctx.Err() != nil {
+				return errReconnect
+			}
+
+			err = fmt.Errorf("cannot reconnect: %w", errReconnect)
+			continue
+		}
+
+		if err = f(); err == nil || !c.opts.Retryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (c *reconnectConsole) Open(ctx context.Context, host *host.Host) error {
+	c.ctx = ctx
+	c.host = host
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+	c.privileged = c.Console.Privileged()
+
+	return nil
+}
+
+func (c *reconnectConsole) Execute(cmd string) (out string, err error) {
+	err = c.do(func() error {
+		out, err = c.Console.Execute(cmd)
+		return err
+	})
+
+	return
+}
+
+func (c *reconnectConsole) ExecuteContext(ctx context.Context, cmd string) (out string, err error) {
+	err = c.do(func() error {
+		out, err = c.Console.ExecuteContext(ctx, cmd)
+		return err
+	})
+
+	return
+}
+
+func (c *reconnectConsole) ExecutePrivileged(cmd string) (out string, err error) {
+	err = c.do(func() error {
+		out, err = c.Console.ExecutePrivileged(cmd)
+		return err
+	})
+
+	return
+}
+
+func (c *reconnectConsole) GetCommandResultReader(cmd string) (r io.Reader, err error) {
+	err = c.do(func() error {
+		r, err = c.Console.GetCommandResultReader(cmd)
+		return err
+	})
+
+	return
+}
+
+func (c *reconnectConsole) Run(cmd string) error {
+	return c.do(func() error {
+		return c.Console.Run(cmd)
+	})
+}
+
+func (c *reconnectConsole) RunContext(ctx context.Context, cmd string) error {
+	return c.do(func() error {
+		return c.Console.RunContext(ctx, cmd)
+	})
+}
+
+func (c *reconnectConsole) Send(cmd string) error {
+	return c.do(func() error {
+		return c.Console.Send(cmd)
+	})
+}
+
+func (c *reconnectConsole) Sendln(cmd string) error {
+	return c.do(func() error {
+		return c.Console.Sendln(cmd)
+	})
+}
+
+func (c *reconnectConsole) SetPrompt(pattern string) error {
+	return c.do(func() error {
+		if err := c.Console.SetPrompt(pattern); err != nil {
+			return err
+		}
+		c.prompt = pattern
+
+		return nil
+	})
+}
+
+func (c *reconnectConsole) Enable() error {
+	return c.do(func() error {
+		c.prompt = ""
+		return c.Console.Enable()
+	})
+}
+
+func (c *reconnectConsole) Disable() error {
+	return c.do(func() error {
+		c.prompt = ""
+		return c.Console.Disable()
+	})
+}
+
+// NewReconnectConsole returns Console that restores the session to the host when the transport fails.
+func NewReconnectConsole(c Console, opts ReconnectOptions) Console {
+	if opts.Retryable == nil {
+		opts.Retryable = IsConnectionError
+	}
+
+	return &reconnectConsole{
+		Console: c,
+		opts:    opts,
+	}
+}
@@ -0,0 +1,135 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jgivc/console/host"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeConsole struct {
+	Console
+	opens    int
+	closes   int
+	runs     []string
+	runErr   error
+	failures []error
+}
+
+func (c *fakeConsole) Open(ctx context.Context, host *host.Host) error {
+	c.opens++
+	return nil
+}
+
+func (c *fakeConsole) Run(cmd string) error {
+	c.runs = append(c.runs, cmd)
+	return c.runErr
+}
+
+func (c *fakeConsole) Execute(cmd string) (string, error) {
+	if len(c.failures) > 0 {
+		err := c.failures[0]
+		c.failures = c.failures[1:]
+
+		return "", err
+	}
+
+	return cmd + " done", nil
+}
+
+func (c *fakeConsole) Enable() error    { return nil }
+func (c *fakeConsole) Disable() error   { return nil }
+func (c *fakeConsole) Privileged() bool { return true }
+
+func (c *fakeConsole) Close() error {
+	c.closes++
+	return nil
+}
+
+type ReconnectTestSuite struct {
+	suite.Suite
+	fake   *fakeConsole
+	events []ReconnectEvent
+	c      Console
+}
+
+func (suite *ReconnectTestSuite) SetupTest() {
+	suite.fake = new(fakeConsole)
+	suite.events = nil
+	suite.c = NewReconnectConsole(suite.fake, ReconnectOptions{
+		InitialCommands: []string{"term le 0"},
+		Attempts:        2,
+		OnReconnect: func(ev ReconnectEvent) {
+			suite.events = append(suite.events, ev)
+		},
+	})
+	suite.Require().NoError(suite.c.Open(context.Background(), &host.Host{Host: "sw1"}))
+}
+
+func (suite *ReconnectTestSuite) TestReconnect() {
+	suite.fake.failures = []error{fmt.Errorf("cannot execute cmd: %w", ErrConnectionLost)}
+
+	out, err := suite.c.Execute("sh ver")
+	suite.NoError(err)
+	suite.Equal("sh ver done", out)
+	suite.Equal(2, suite.fake.opens)
+	suite.Equal(1, suite.fake.closes)
+	suite.Equal([]string{"term le 0", "term le 0"}, suite.fake.runs)
+	suite.Require().Len(suite.events, 1)
+	suite.Equal(1, suite.events[0].Attempt)
+	suite.NoError(suite.events[0].Err)
+	suite.ErrorIs(suite.events[0].Cause, ErrConnectionLost)
+}
+
+func (suite *ReconnectTestSuite) TestAttemptsExceeded() {
+	suite.fake.failures = []error{ErrConnectionLost, ErrConnectionLost, ErrConnectionLost}
+
+	_, err := suite.c.Execute("sh ver")
+	suite.ErrorIs(err, ErrConnectionLost)
+	suite.Equal(3, suite.fake.opens)
+	suite.Len(suite.events, 2)
+}
+
+func (suite *ReconnectTestSuite) TestNotRetryable() {
+	errCmd := errors.New("invalid input")
+	suite.fake.failures = []error{errCmd}
+
+	_, err := suite.c.Execute("sh ver")
+	suite.ErrorIs(err, errCmd)
+	suite.Equal(1, suite.fake.opens)
+	suite.Empty(suite.events)
+}
+
+func (suite *ReconnectTestSuite) TestInitialCommandFailed() {
+	errCmd := errors.New("invalid input")
+
+	var ignored []string
+
+	fake := &fakeConsole{runErr: errCmd}
+	c := NewReconnectConsole(fake, ReconnectOptions{
+		InitialCommands: []string{"term le 0"},
+		OnInitialCommandError: func(cmd string, err error) {
+			ignored = append(ignored, fmt.Sprintf("%s: %v", cmd, err))
+		},
+	})
+	suite.NoError(c.Open(context.Background(), &host.Host{Host: "sw1"}))
+	suite.Equal([]string{"term le 0: invalid input"}, ignored)
+
+	c = NewReconnectConsole(fake, ReconnectOptions{
+		InitialCommands:       []string{"term le 0"},
+		StrictInitialCommands: true,
+	})
+	suite.ErrorIs(c.Open(context.Background(), &host.Host{Host: "sw1"}), errCmd)
+
+	// A broken session always fails.
+	fake.runErr = ErrConnectionLost
+	c = NewReconnectConsole(fake, ReconnectOptions{InitialCommands: []string{"term le 0"}})
+	suite.ErrorIs(c.Open(context.Background(), &host.Host{Host: "sw1"}), ErrConnectionLost)
+}
+
+func TestReconnectTestSuite(t *testing.T) {
+	suite.Run(t, new(ReconnectTestSuite))
+}
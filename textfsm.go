@@ -0,0 +1,28 @@
+package console
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jgivc/console/textfsm"
+)
+
+// ExecuteTemplate executes the command and parses the output with the TextFSM template.
+// The command echo and the prompt are not parsed.
+func ExecuteTemplate(c Console, cmd string, t *textfsm.Template) ([]textfsm.Record, error) {
+	return ExecuteTemplateContext(context.Background(), c, cmd, t)
+}
+
+func ExecuteTemplateContext(ctx context.Context, c Console, cmd string, t *textfsm.Template) ([]textfsm.Record, error) {
+	out, err := c.ExecuteContext(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := t.Parse(CommandOutput(cmd, out))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse output of %s: %w", cmd, err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,233 @@
+package textfsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Record is one parsed row. The values are string, List values are []string.
+type Record map[string]interface{}
+
+type value struct {
+	def      *valueDef
+	s        string
+	list     []string
+	filldown []string // List values keep the filled down items here
+	set      bool
+}
+
+func (v *value) empty() bool {
+	if v.def.list {
+		return len(v.list) == 0
+	}
+
+	return !v.set
+}
+
+func (v *value) get() interface{} {
+	if v.def.list {
+		return append([]string{}, v.list...)
+	}
+
+	return v.s
+}
+
+func (v *value) assign(s string) {
+	if v.def.list {
+		v.list = append(v.list, s)
+		if v.def.filldown {
+			v.filldown = append([]string{}, v.list...)
+		}
+
+		return
+	}
+
+	v.s, v.set = s, true
+}
+
+func (v *value) clear() {
+	if v.def.filldown {
+		if v.def.list {
+			v.list = append([]string{}, v.filldown...)
+		}
+
+		return
+	}
+
+	v.s, v.set, v.list = "", false, nil
+}
+
+func (v *value) clearAll() {
+	v.s, v.set, v.list, v.filldown = "", false, nil, nil
+}
+
+type parser struct {
+	values  []*value
+	records []Record
+	empty   []map[string]bool // values that were empty in the appended records, for Fillup
+}
+
+func (p *parser) assign(name, s string) {
+	for _, v := range p.values {
+		if v.def.name != name {
+			continue
+		}
+
+		v.assign(s)
+
+		if !v.def.fillup {
+			return
+		}
+
+		// Fill the value up in the previous records until a non empty one.
+		for j := len(p.records) - 1; j >= 0 && p.empty[j][name]; j-- {
+			p.records[j][name] = s
+			p.empty[j][name] = false
+		}
+
+		return
+	}
+}
+
+func (p *parser) clear() {
+	for _, v := range p.values {
+		v.clear()
+	}
+}
+
+func (p *parser) clearAll() {
+	for _, v := range p.values {
+		v.clearAll()
+	}
+}
+
+func (p *parser) appendRecord() {
+	allEmpty := true
+
+	for _, v := range p.values {
+		if v.def.required && v.empty() {
+			p.clear()
+			return
+		}
+
+		if !v.empty() {
+			allEmpty = false
+		}
+	}
+
+	if allEmpty {
+		return
+	}
+
+	r := make(Record, len(p.values))
+	empty := make(map[string]bool)
+
+	for _, v := range p.values {
+		r[v.def.name] = v.get()
+		empty[v.def.name] = v.empty()
+	}
+
+	p.records = append(p.records, r)
+	p.empty = append(p.empty, empty)
+	p.clear()
+}
+
+/*
+line matches the line with the rules of the state.
+It returns the new state name or an empty string if the state is not changed.
+*/
+func (p *parser) line(rules []*rule, text string) (string, error) {
+	for _, r := range rules {
+		m := r.re.FindStringSubmatchIndex(text)
+		if m == nil {
+			continue
+		}
+
+		// The optional groups that did not participate in the match are not assigned.
+		for i, name := range r.re.SubexpNames() {
+			if name != "" && m[2*i] >= 0 {
+				p.assign(name, text[m[2*i]:m[2*i+1]])
+			}
+		}
+
+		switch r.recordOp {
+		case recordRecord:
+			p.appendRecord()
+		case recordClear:
+			p.clear()
+		case recordClearall:
+			p.clearAll()
+		}
+
+		switch r.lineOp {
+		case lineError:
+			msg := r.errorMsg
+			if msg == "" {
+				msg = "state error"
+			}
+
+			return "", fmt.Errorf("%w: %s: rule line %d: %q", ErrParse, msg, r.line, text)
+		case lineContinue:
+			continue
+		}
+
+		return r.newState, nil
+	}
+
+	return "", nil
+}
+
+// Parse parses the text and returns the records.
+func (t *Template) Parse(text string) ([]Record, error) {
+	p := &parser{}
+	for _, def := range t.values {
+		p.values = append(p.values, &value{def: def})
+	}
+
+	state := stateStart
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	for _, line := range strings.Split(text, "\n") {
+		newState, err := p.line(t.states[state], line)
+		if err != nil {
+			return nil, err
+		}
+
+		if newState != "" {
+			state = newState
+		}
+
+		if state == stateEnd || state == stateEOF {
+			break
+		}
+	}
+
+	// Implicit EOF state records the last values.
+	if _, exists := t.states[stateEOF]; !exists && state != stateEnd {
+		p.appendRecord()
+	}
+
+	return p.records, nil
+}
+
+// ParseRows parses the text and returns the records as rows in the Header order.
+func (t *Template) ParseRows(text string) ([][]interface{}, error) {
+	records, err := t.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	header := t.Header()
+	rows := make([][]interface{}, 0, len(records))
+
+	for _, r := range records {
+		row := make([]interface{}, 0, len(header))
+		for _, name := range header {
+			row = append(row, r[name])
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,327 @@
+/*
+Package textfsm parses command output with TextFSM templates (https://github.com/google/textfsm).
+
+Go regexp syntax is used in the templates: (?P<name>...) groups are supported,
+but lookaround assertions and backreferences are not.
+*/
+package textfsm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	stateStart = "Start"
+	stateEnd   = "End"
+	stateEOF   = "EOF"
+
+	optionFilldown = "Filldown"
+	optionKey      = "Key"
+	optionRequired = "Required"
+	optionList     = "List"
+	optionFillup   = "Fillup"
+
+	lineNext     = "Next"
+	lineContinue = "Continue"
+	lineError    = "Error"
+
+	recordRecord   = "Record"
+	recordClear    = "Clear"
+	recordClearall = "Clearall"
+)
+
+var (
+	ErrTemplate = errors.New("invalid template")
+	ErrParse    = errors.New("template error action")
+
+	valueLine   = regexp.MustCompile(`^Value\s+(?:([\w,]+)\s+)?(\w+)\s+(\(.*\))\s*$`)
+	stateName   = regexp.MustCompile(`^\w+$`)
+	ruleLine    = regexp.MustCompile(`^\s+\^`)
+	ruleAction  = regexp.MustCompile(`^(.*?)(?:\s+->\s*(.*))?$`)
+	substitute  = regexp.MustCompile(`\$(?:\$|\{(\w+)\}|(\w+))`)
+	lineAction  = regexp.MustCompile(`^(Next|Continue|Error)(?:\.(NoRecord|Record|Clear|Clearall))?(?:\s+(\w+|".*"))?$`)
+	recordOnly  = regexp.MustCompile(`^(NoRecord|Record|Clear|Clearall)(?:\s+(\w+))?$`)
+	newStateRef = regexp.MustCompile(`^(\w+)$`)
+)
+
+type valueDef struct {
+	name     string
+	regex    string
+	filldown bool
+	key      bool
+	required bool
+	list     bool
+	fillup   bool
+}
+
+type rule struct {
+	re       *regexp.Regexp
+	lineOp   string
+	recordOp string
+	newState string
+	errorMsg string
+	line     int
+}
+
+// Template is the parsed TextFSM template. It is safe for concurrent use.
+type Template struct {
+	values []*valueDef
+	states map[string][]*rule
+}
+
+// Header returns the value names in the template order.
+func (t *Template) Header() []string {
+	names := make([]string, 0, len(t.values))
+	for _, v := range t.values {
+		names = append(names, v.name)
+	}
+
+	return names
+}
+
+// Keys returns the names of the Key values.
+func (t *Template) Keys() []string {
+	var names []string
+
+	for _, v := range t.values {
+		if v.key {
+			names = append(names, v.name)
+		}
+	}
+
+	return names
+}
+
+func templateError(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrTemplate, line, fmt.Sprintf(format, args...))
+}
+
+func parseValue(line int, text string) (*valueDef, error) {
+	m := valueLine.FindStringSubmatch(text)
+	if m == nil {
+		return nil, templateError(line, "bad value definition: %s", text)
+	}
+
+	v := &valueDef{name: m[2], regex: m[3]}
+
+	if m[1] != "" {
+		for _, option := range strings.Split(m[1], ",") {
+			switch option {
+			case optionFilldown:
+				v.filldown = true
+			case optionKey:
+				v.key = true
+			case optionRequired:
+				v.required = true
+			case optionList:
+				v.list = true
+			case optionFillup:
+				v.fillup = true
+			default:
+				return nil, templateError(line, "unknown value option: %s", option)
+			}
+		}
+	}
+
+	if _, err := regexp.Compile(v.regex); err != nil {
+		return nil, templateError(line, "bad value regex: %v", err)
+	}
+
+	return v, nil
+}
+
+func (t *Template) parseRule(line int, text string, regexes map[string]string) (*rule, error) {
+	m := ruleAction.FindStringSubmatch(strings.TrimSpace(text))
+
+	var missing []string
+	match := substitute.ReplaceAllStringFunc(m[1], func(s string) string {
+		if s == "$$" {
+			return "$"
+		}
+
+		sm := substitute.FindStringSubmatch(s)
+		name := sm[1] + sm[2]
+
+		re, exists := regexes[name]
+		if !exists {
+			missing = append(missing, name)
+			return s
+		}
+
+		return re
+	})
+
+	if len(missing) > 0 {
+		return nil, templateError(line, "unknown value: %s", strings.Join(missing, ", "))
+	}
+
+	re, err := regexp.Compile(match)
+	if err != nil {
+		return nil, templateError(line, "bad rule regex: %v", err)
+	}
+
+	r := &rule{re: re, lineOp: lineNext, line: line}
+
+	action := strings.TrimSpace(m[2])
+
+	switch {
+	case action == "":
+	case lineAction.MatchString(action):
+		am := lineAction.FindStringSubmatch(action)
+		r.lineOp, r.recordOp = am[1], am[2]
+
+		if r.lineOp == lineError {
+			r.errorMsg = strings.Trim(am[3], `"`)
+		} else {
+			r.newState = am[3]
+		}
+	case recordOnly.MatchString(action):
+		am := recordOnly.FindStringSubmatch(action)
+		r.recordOp, r.newState = am[1], am[2]
+	case newStateRef.MatchString(action):
+		r.newState = action
+	default:
+		return nil, templateError(line, "bad rule action: %s", action)
+	}
+
+	if r.lineOp == lineContinue && r.newState != "" {
+		return nil, templateError(line, "Continue cannot change state")
+	}
+
+	return r, nil
+}
+
+func (t *Template) checkStates() error {
+	if _, exists := t.states[stateStart]; !exists {
+		return fmt.Errorf("%w: no Start state", ErrTemplate)
+	}
+
+	if rules := t.states[stateEnd]; len(rules) > 0 {
+		return fmt.Errorf("%w: End state must be empty", ErrTemplate)
+	}
+
+	if rules := t.states[stateEOF]; len(rules) > 0 {
+		return fmt.Errorf("%w: EOF state must be empty", ErrTemplate)
+	}
+
+	for _, rules := range t.states {
+		for _, r := range rules {
+			if r.newState == "" || r.newState == stateEnd || r.newState == stateEOF {
+				continue
+			}
+
+			if _, exists := t.states[r.newState]; !exists {
+				return templateError(r.line, "unknown state: %s", r.newState)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Parse reads the template.
+func Parse(r io.Reader) (*Template, error) {
+	t := &Template{states: make(map[string][]*rule)}
+	regexes := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+
+	var (
+		line   int
+		values = true
+		state  string
+	)
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+
+		if values {
+			if text == "" {
+				values = false
+				continue
+			}
+
+			v, err := parseValue(line, text)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, exists := regexes[v.name]; exists {
+				return nil, templateError(line, "duplicate value: %s", v.name)
+			}
+
+			t.values = append(t.values, v)
+			regexes[v.name] = "(?P<" + v.name + ">" + v.regex[1:]
+
+			continue
+		}
+
+		switch {
+		case text == "":
+			state = ""
+		case stateName.MatchString(text):
+			if _, exists := t.states[text]; exists {
+				return nil, templateError(line, "duplicate state: %s", text)
+			}
+
+			state = text
+			t.states[state] = nil
+		case ruleLine.MatchString(text):
+			if state == "" {
+				return nil, templateError(line, "rule outside of state")
+			}
+
+			r, err := t.parseRule(line, text, regexes)
+			if err != nil {
+				return nil, err
+			}
+
+			t.states[state] = append(t.states[state], r)
+		default:
+			return nil, templateError(line, "unexpected line: %s", text)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read template: %w", err)
+	}
+
+	if len(t.values) == 0 {
+		return nil, fmt.Errorf("%w: no values", ErrTemplate)
+	}
+
+	if err := t.checkStates(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func ParseString(s string) (*Template, error) {
+	return Parse(strings.NewReader(s))
+}
+
+func ParseFile(fileName string) (*Template, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open template: %w", err)
+	}
+	defer f.Close()
+
+	t, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	return t, nil
+}
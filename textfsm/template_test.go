@@ -0,0 +1,150 @@
+package textfsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const showIPIntBrief = `Interface              IP-Address      OK? Method Status                Protocol
+GigabitEthernet0/0     10.0.0.1        YES manual up                    up
+GigabitEthernet0/1     unassigned      YES unset  administratively down down
+Vlan1                  unassigned      YES unset  down                  down
+`
+
+const showVlan = `VLAN Name                             Status    Ports
+---- -------------------------------- --------- -------------------------------
+1    default                          active    Gi0/1, Gi0/2
+                                                Gi0/3
+10   users                            active    Gi0/4
+20   empty                            active
+`
+
+const vlanTemplate = `# Ports continue on the next lines
+Value Required VLAN_ID (\d+)
+Value NAME (\S+)
+Value List PORTS ([\w/]+)
+
+Start
+  ^VLAN -> Vlans
+
+Vlans
+  ^\d+ -> Continue.Record
+  ^${VLAN_ID}\s+${NAME}\s+active -> Continue
+  ^\d+\s+\S+\s+active\s+${PORTS} -> Continue
+  ^\d+\s+\S+\s+active\s+[\w/]+,\s+${PORTS}
+  ^\s+${PORTS}$$
+`
+
+type TemplateTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TemplateTestSuite) TestRecord() {
+	t, err := ParseFile("testdata/cisco_ios_show_ip_interface_brief.textfsm")
+	suite.Require().NoError(err)
+	suite.Equal([]string{"INTF", "IPADDR", "STATUS", "PROTO"}, t.Header())
+
+	records, err := t.Parse(showIPIntBrief)
+	suite.Require().NoError(err)
+	suite.Equal([]Record{
+		{"INTF": "GigabitEthernet0/0", "IPADDR": "10.0.0.1", "STATUS": "up", "PROTO": "up"},
+		{"INTF": "GigabitEthernet0/1", "IPADDR": "unassigned", "STATUS": "administratively down", "PROTO": "down"},
+		{"INTF": "Vlan1", "IPADDR": "unassigned", "STATUS": "down", "PROTO": "down"},
+	}, records)
+
+	rows, err := t.ParseRows(showIPIntBrief)
+	suite.Require().NoError(err)
+	suite.Len(rows, 3)
+	suite.Equal([]interface{}{"Vlan1", "unassigned", "down", "down"}, rows[2])
+}
+
+func (suite *TemplateTestSuite) TestListContinue() {
+	t, err := ParseString(vlanTemplate)
+	suite.Require().NoError(err)
+
+	records, err := t.Parse(showVlan)
+	suite.Require().NoError(err)
+	suite.Equal([]Record{
+		{"VLAN_ID": "1", "NAME": "default", "PORTS": []string{"Gi0/1", "Gi0/2", "Gi0/3"}},
+		{"VLAN_ID": "10", "NAME": "users", "PORTS": []string{"Gi0/4"}},
+		{"VLAN_ID": "20", "NAME": "empty", "PORTS": []string{}},
+	}, records)
+}
+
+func (suite *TemplateTestSuite) TestFilldownFillup() {
+	t, err := ParseString(`Value Filldown,Key VRF (\S+)
+Value Required PREFIX (\S+)
+Value Fillup AS (\d+)
+
+Start
+  ^VRF ${VRF}
+  ^route ${PREFIX} -> Record
+  ^as ${AS}
+  ^end -> End
+`)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"VRF"}, t.Keys())
+
+	records, err := t.Parse("VRF a\nroute 10.0.0.0/8\nroute 10.1.0.0/16\nas 65000\nVRF b\nroute 0.0.0.0/0\nend\nroute 1.1.1.1/32\n")
+	suite.Require().NoError(err)
+	// AS fills up the recorded rows and stays in the current one.
+	suite.Equal([]Record{
+		{"VRF": "a", "PREFIX": "10.0.0.0/8", "AS": "65000"},
+		{"VRF": "a", "PREFIX": "10.1.0.0/16", "AS": "65000"},
+		{"VRF": "b", "PREFIX": "0.0.0.0/0", "AS": "65000"},
+	}, records)
+}
+
+func (suite *TemplateTestSuite) TestClearallEOF() {
+	t, err := ParseString(`Value Filldown HOST (\S+)
+Value USER (\S+)
+
+Start
+  ^host ${HOST}
+  ^user ${USER} -> Record
+  ^reset -> Clearall
+  ^stop -> EOF
+
+EOF
+`)
+	suite.Require().NoError(err)
+
+	records, err := t.Parse("host a\nuser x\nreset\nuser y\nhost b\nstop\nuser z\n")
+	suite.Require().NoError(err)
+	// The explicit EOF state disables the implicit record of HOST b.
+	suite.Equal([]Record{
+		{"HOST": "a", "USER": "x"},
+		{"HOST": "", "USER": "y"},
+	}, records)
+}
+
+func (suite *TemplateTestSuite) TestError() {
+	t, err := ParseString("Value A (\\S+)\n\nStart\n  ^ok ${A} -> Record\n  ^. -> Error \"unexpected line\"\n")
+	suite.Require().NoError(err)
+
+	_, err = t.Parse("ok 1\n% Invalid input\n")
+	suite.ErrorIs(err, ErrParse)
+	suite.ErrorContains(err, "unexpected line")
+}
+
+func (suite *TemplateTestSuite) TestBadTemplate() {
+	for _, text := range []string{
+		"",
+		"Value A (\\S+)\n\nState\n  ^x\n",
+		"Value A \\S+\n\nStart\n",
+		"Value Bad A (\\S+)\n\nStart\n",
+		"Value A (\\S+)\n\nStart\n  ^${B}\n",
+		"Value A (\\S+)\n\nStart\n  ^x -> Continue Other\n\nOther\n",
+		"Value A (\\S+)\n\nStart\n  ^x -> Missing\n",
+		"Value A (\\S+)\n\nStart\n  ^x -> Next.Bad\n",
+		"Value A (\\S+)\n\nStart\n\nEnd\n  ^x\n",
+	} {
+		_, err := ParseString(text)
+		suite.ErrorIs(err, ErrTemplate, text)
+	}
+}
+
+func TestTemplateTestSuite(t *testing.T) {
+	suite.Run(t, new(TemplateTestSuite))
+}
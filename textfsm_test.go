@@ -0,0 +1,61 @@
+package console
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jgivc/console/textfsm"
+	"github.com/stretchr/testify/suite"
+)
+
+type outputConsole struct {
+	Console
+	out string
+}
+
+func (c *outputConsole) ExecuteContext(ctx context.Context, cmd string) (string, error) {
+	return c.out, nil
+}
+
+func (c *outputConsole) Execute(cmd string) (string, error) {
+	return c.out, nil
+}
+
+type TemplateTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TemplateTestSuite) TestExecuteTemplate() {
+	t, err := textfsm.ParseString("Value NAME (\\S+)\nValue VERSION (\\S+)\n\nStart\n  ^${NAME} uptime\n  ^Version ${VERSION}\n")
+	suite.Require().NoError(err)
+
+	records, err := ExecuteTemplate(&outputConsole{out: "sw1 uptime is 1 week\nVersion 15.2\nsw1#"}, "show version", t)
+	suite.Require().NoError(err)
+	suite.Equal([]textfsm.Record{{"NAME": "sw1", "VERSION": "15.2"}}, records)
+
+	t, err = textfsm.ParseString("Value NAME (\\S+)\n\nStart\n  ^% Invalid -> Error\n")
+	suite.Require().NoError(err)
+
+	_, err = ExecuteTemplate(&outputConsole{out: "% Invalid input\nsw1#"}, "show version", t)
+	suite.ErrorIs(err, textfsm.ErrParse)
+}
+
+// TestErrorRule checks the templates ending with ^. -> Error, as most of ntc-templates do.
+func (suite *TemplateTestSuite) TestErrorRule() {
+	t, err := textfsm.ParseFile("textfsm/testdata/cisco_ios_show_ip_interface_brief.textfsm")
+	suite.Require().NoError(err)
+
+	out := "sh ip int br\r\n" +
+		"Interface              IP-Address      OK? Method Status                Protocol\r\n" +
+		"GigabitEthernet0/0     10.0.0.1        YES NVRAM  up                    up\r\n" +
+		"sw1#"
+
+	records, err := ExecuteTemplate(&outputConsole{out: out}, "sh ip int br", t)
+	suite.Require().NoError(err)
+	suite.Equal([]textfsm.Record{{"INTF": "GigabitEthernet0/0", "IPADDR": "10.0.0.1", "STATUS": "up", "PROTO": "up"}},
+		records)
+}
+
+func TestTemplateTestSuite(t *testing.T) {
+	suite.Run(t, new(TemplateTestSuite))
+}
@@ -0,0 +1,178 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jgivc/console/host"
+)
+
+const (
+	captureFilePerm  = 0600
+	captureRedacted  = "<redacted>"
+	captureRoundTime = time.Millisecond
+	captureMinSecret = 4 // Shorter secrets are not redacted, they would corrupt unrelated text
+)
+
+/*
+capturer wraps the Transport and records every received chunk with the delay since the previous one.
+On Close the chunks are written to dir/host.xml as a scenario for the dummy transport.
+The passwords of the account and the ones sent with WriteSecret are redacted from the captured data,
+the username is kept, so the output such as show users matches the device.
+*/
+type capturer struct {
+	Transport
+	dir     string
+	mu      sync.Mutex
+	host    *host.Host
+	last    time.Time
+	data    ReaderData
+	secrets [][]byte
+}
+
+func (c *capturer) Open(ctx context.Context, h *host.Host) error {
+	c.host = h
+	c.last = time.Now()
+	c.data = ReaderData{}
+	c.secrets = nil
+
+	for _, s := range []string{h.EnablePassword, h.Password} {
+		c.addSecret([]byte(s))
+	}
+
+	return c.Transport.Open(ctx, h)
+}
+
+// addSecret adds the secret to redact, c.mu must be held or the capture not started.
+func (c *capturer) addSecret(s []byte) {
+	if len(s) < captureMinSecret {
+		return
+	}
+
+	for _, secret := range c.secrets {
+		if bytes.Equal(secret, s) {
+			return
+		}
+	}
+
+	c.secrets = append(c.secrets, append([]byte(nil), s...))
+
+	// Longer secrets first, so a secret containing another one is redacted as a whole.
+	sort.Slice(c.secrets, func(i, j int) bool {
+		return len(c.secrets[i]) > len(c.secrets[j])
+	})
+}
+
+func (c *capturer) redact(b []byte) []byte {
+	for _, s := range c.secrets {
+		b = bytes.ReplaceAll(b, s, []byte(captureRedacted))
+	}
+
+	return b
+}
+
+func (c *capturer) Read(b []byte) (int, error) {
+	n, err := c.Transport.Read(b)
+	if n < 1 {
+		return n, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	sd := SendData{
+		Timeout: ReadTimeout(now.Sub(c.last).Round(captureRoundTime)),
+		Send:    c.redact(append([]byte(nil), b[:n]...)),
+	}
+	c.last = now
+
+	if !validXMLText(sd.Send) {
+		sd.Encoding = EncodingBase64
+		sd.Send = []byte(base64.StdEncoding.EncodeToString(sd.Send))
+	}
+
+	c.data.SendData = append(c.data.SendData, sd)
+
+	return n, err
+}
+
+// validXMLText reports whether b can be stored as XML character data as is.
+func validXMLText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *capturer) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.host == nil {
+		return nil
+	}
+
+	out, err := xml.MarshalIndent(&c.data, "", "    ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal scenario: %w", err)
+	}
+
+	fileName := path.Join(c.dir, fmt.Sprintf("%s.xml", c.host.Host))
+	if err2 := os.WriteFile(fileName, append([]byte(xml.Header), out...), captureFilePerm); err2 != nil {
+		return fmt.Errorf("cannot write scenario: %w", err2)
+	}
+
+	c.host = nil
+
+	return nil
+}
+
+func (c *capturer) WriteSecret(b []byte) (int, error) {
+	c.mu.Lock()
+	c.addSecret(b)
+	c.mu.Unlock()
+
+	if sw, ok := c.Transport.(SecretWriter); ok {
+		return sw.WriteSecret(b)
+	}
+
+	return c.Transport.Write(b)
+}
+
+func (c *capturer) KeepAlive() error {
+	if ka, ok := c.Transport.(KeepAliver); ok {
+		return ka.KeepAlive()
+	}
+
+	return ErrNotSupported
+}
+
+func (c *capturer) Close() error {
+	return errors.Join(c.Transport.Close(), c.save())
+}
+
+// NewCapturer returns Transport that saves the session of t to dir/host.xml as a dummy transport scenario.
+func NewCapturer(t Transport, dir string) Transport {
+	return &capturer{
+		Transport: t,
+		dir:       dir,
+	}
+}
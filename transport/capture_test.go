@@ -0,0 +1,151 @@
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/jgivc/console/host"
+	"github.com/stretchr/testify/suite"
+)
+
+type CaptureTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *CaptureTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *CaptureTestSuite) writeScenario(rd *ReaderData) string {
+	out, err := xml.Marshal(rd)
+	suite.Require().NoError(err)
+
+	fileName := path.Join(suite.dir, "source.xml")
+	suite.Require().NoError(os.WriteFile(fileName, out, 0600))
+
+	return fileName
+}
+
+func (suite *CaptureTestSuite) readAll(t Transport) [][]byte {
+	var chunks [][]byte
+
+	b := make([]byte, 1024)
+	for {
+		n, err := t.Read(b)
+		if n > 0 {
+			chunks = append(chunks, append([]byte(nil), b[:n]...))
+		}
+
+		if err != nil && n < 1 {
+			suite.Require().ErrorIs(err, io.EOF)
+			return chunks
+		}
+	}
+}
+
+func (suite *CaptureTestSuite) TestReplay() {
+	source := &ReaderData{SendData: []SendData{
+		{Timeout: ReadTimeout(20 * time.Millisecond), Send: []byte("Username: ")},
+		{Timeout: ReadTimeout(20 * time.Millisecond), Send: []byte("admin\r\nPassword: ")},
+		{
+			Timeout:  ReadTimeout(30 * time.Millisecond),
+			Encoding: EncodingBase64,
+			Send:     []byte(base64.StdEncoding.EncodeToString([]byte("\x1b[2Jsw1#"))),
+		},
+		{Send: []byte("sw1#sh run\r\nusername admin secret p@ss\r\nsw1#")},
+	}}
+
+	h := &host.Host{
+		Host:    "sw1",
+		Account: host.Account{Username: "admin", Password: "p@ss"},
+	}
+
+	captured := NewCapturer(&dummyTransport{fileName: suite.writeScenario(source), timeout: time.Second}, suite.dir)
+	suite.Require().NoError(captured.Open(context.Background(), h))
+	suite.Len(suite.readAll(captured), len(source.SendData))
+	suite.Require().NoError(captured.Close())
+
+	replay := &dummyTransport{fileName: path.Join(suite.dir, "sw1.xml"), timeout: time.Second}
+	suite.Require().NoError(replay.Open(context.Background(), h))
+	chunks := suite.readAll(replay)
+	suite.Require().NoError(replay.Close())
+
+	suite.Equal([][]byte{
+		[]byte("Username: "),
+		[]byte("admin\r\nPassword: "),
+		[]byte("\x1b[2Jsw1#"),
+		[]byte("sw1#sh run\r\nusername admin secret <redacted>\r\nsw1#"),
+	}, chunks)
+
+	suite.Equal(2, encodedChunk(suite, path.Join(suite.dir, "sw1.xml")))
+	suite.InDelta(30*time.Millisecond, time.Duration(replay.dr.rd.SendData[2].Timeout), float64(10*time.Millisecond))
+}
+
+func (suite *CaptureTestSuite) TestRedactSent() {
+	source := &ReaderData{SendData: []SendData{{Send: []byte("Password: ")}, {Send: []byte("enable-pw ab sw1#")}}}
+
+	h := &host.Host{Host: "sw1", Account: host.Account{Username: "admin", Password: "ab"}}
+
+	captured := NewCapturer(&dummyTransport{fileName: suite.writeScenario(source), timeout: time.Second}, suite.dir)
+	suite.Require().NoError(captured.Open(context.Background(), h))
+	_, err := captured.(SecretWriter).WriteSecret([]byte("enable-pw"))
+	suite.Require().NoError(err)
+	suite.readAll(captured)
+	suite.Require().NoError(captured.Close())
+
+	b, err := os.ReadFile(path.Join(suite.dir, "sw1.xml"))
+	suite.Require().NoError(err)
+
+	// The short password is not redacted.
+	suite.Contains(string(b), "&lt;redacted&gt; ab sw1#")
+}
+
+// encodedChunk returns the index of the first base64 encoded chunk in the scenario file.
+func encodedChunk(suite *CaptureTestSuite, fileName string) int {
+	b, err := os.ReadFile(fileName)
+	suite.Require().NoError(err)
+
+	var raw struct {
+		SendData []SendData `xml:"send"`
+	}
+	suite.Require().NoError(xml.Unmarshal(b, &raw))
+
+	for i := range raw.SendData {
+		if raw.SendData[i].Encoding == EncodingBase64 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (suite *CaptureTestSuite) TestShortBuffer() {
+	source := &ReaderData{SendData: []SendData{{Send: []byte("0123456789")}}}
+
+	tr := &dummyTransport{fileName: suite.writeScenario(source), timeout: time.Second}
+	suite.Require().NoError(tr.Open(context.Background(), nil))
+
+	b := make([]byte, 4)
+	var out []byte
+	for {
+		n, err := tr.Read(b)
+		out = append(out, b[:n]...)
+		if err != nil {
+			suite.ErrorIs(err, io.EOF)
+			break
+		}
+	}
+
+	suite.Equal("0123456789", string(out))
+}
+
+func TestCaptureTestSuite(t *testing.T) {
+	suite.Run(t, new(CaptureTestSuite))
+}
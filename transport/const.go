@@ -11,4 +11,7 @@ const (
 	sshTtyOpOSpeed    = 115200
 	sshTerminalWidth  = 80
 	sshTerminalHeight = 1000
+
+	sshKeepAliveRequest = "keepalive@openssh.com"
+	sshAuthFailed       = "unable to authenticate"
 )
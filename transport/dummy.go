@@ -1,11 +1,14 @@
 package transport
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/jgivc/console/host"
@@ -24,55 +27,226 @@ func (t *ReadTimeout) UnmarshalXMLAttr(attr xml.Attr) error {
 	return nil
 }
 
+func (t ReadTimeout) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if t < 1 {
+		return xml.Attr{}, nil
+	}
+
+	return xml.Attr{Name: name, Value: time.Duration(t).String()}, nil
+}
+
+const EncodingBase64 = "base64"
+
+// SendData is one chunk sent by the device after Timeout. Chunks with bytes
+// that cannot be written to XML are stored with encoding="base64".
 type SendData struct {
-	Timeout ReadTimeout `xml:"timeout,attr"`
-	Send    []byte      `xml:",chardata"`
+	Timeout  ReadTimeout `xml:"timeout,attr"`
+	Encoding string      `xml:"encoding,attr,omitempty"`
+	Send     []byte      `xml:",chardata"`
 }
 
+func (sd *SendData) decode() error {
+	switch sd.Encoding {
+	case "":
+	case EncodingBase64:
+		b, err := base64.StdEncoding.DecodeString(string(sd.Send))
+		if err != nil {
+			return fmt.Errorf("cannot decode send data: %w", err)
+		}
+
+		sd.Send = b
+		sd.Encoding = ""
+	default:
+		return fmt.Errorf("unknown send data encoding: %s", sd.Encoding)
+	}
+
+	return nil
+}
+
+// ReaderData is the dummy transport scenario. SendData holds the top level send steps.
 type ReaderData struct {
-	XMLName  xml.Name   `xml:"scenario"`
+	XMLName xml.Name `xml:"scenario"`
+	Impairments
 	SendData []SendData `xml:"send"`
+	Steps    Steps      `xml:"-"`
 }
 
 type dummyReader struct {
-	i       int
+	steps   Steps     // Steps left to play
+	off     int       // Sent bytes of the current chunk
+	frag    int       // Bytes left of the current fragment, 0 - the next fragment is not scheduled
+	readyAt time.Time // When the current fragment is due
 	timeout time.Duration
+	imp     *impairer
 	rd      ReaderData
 	done    chan struct{}
+	err     error // Scenario failure, returned by every following Read
+
+	mu      sync.Mutex
+	written bytes.Buffer  // Data written by the console and not matched yet
+	skipLF  bool          // The last line ended with \r, so the next \n is the part of it
+	notify  chan struct{} // Signals the write
+}
+
+func (r *dummyReader) write(b []byte) {
+	r.mu.Lock()
+	r.written.Write(b)
+	r.mu.Unlock()
+
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// popLine returns the next line written by the console without the line ending.
+func (r *dummyReader) popLine() (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.written.Bytes()
+	if r.skipLF && len(b) > 0 {
+		r.skipLF = false
+		if b[0] == '\n' {
+			r.written.Next(1)
+			b = b[1:]
+		}
+	}
+
+	i := bytes.IndexAny(b, "\r\n")
+	if i < 0 {
+		return "", false
+	}
+
+	line := string(b[:i])
+	r.skipLF = b[i] == '\r'
+	r.written.Next(i + 1)
+
+	return line, true
+}
+
+func (r *dummyReader) waitLine() (string, error) {
+	deadline := time.After(r.timeout)
+
+	for {
+		if line, ok := r.popLine(); ok {
+			return line, nil
+		}
+
+		select {
+		case <-r.done:
+			return "", fmt.Errorf("interrupted")
+		case <-deadline:
+			return "", os.ErrDeadlineExceeded
+		case <-r.notify:
+		}
+	}
+}
+
+func (r *dummyReader) fail(format string, args ...interface{}) error {
+	r.err = fmt.Errorf("%w: "+format, append([]interface{}{ErrUnexpectedInput}, args...)...)
+	return r.err
+}
+
+// react plays the expect or choice step against the next written line.
+func (r *dummyReader) react(step Step) error {
+	line, err := r.waitLine()
+	if err != nil {
+		return err
+	}
+
+	if step.Expect != nil {
+		if !step.Expect.Match(line) {
+			return r.fail("expected %s, got %q", step.Expect, line)
+		}
+
+		r.steps = r.steps[1:]
+
+		return nil
+	}
+
+	for _, w := range step.Choice.When {
+		if w.Match(line) {
+			r.steps = append(append(Steps(nil), w.Steps...), r.steps[1:]...)
+			return nil
+		}
+	}
+
+	return r.fail("expected %s, got %q", step.Choice, line)
 }
 
 func (r *dummyReader) Read(b []byte) (int, error) {
-	if r.i >= len(r.rd.SendData) {
-		return 0, io.EOF
+	for {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		if len(r.steps) == 0 {
+			return 0, io.EOF
+		}
+
+		step := r.steps[0]
+
+		switch {
+		case step.Send != nil:
+			return r.send(b, step.Send)
+		case step.Error != nil:
+			r.err = fmt.Errorf("%w: %s", ErrInjected, *step.Error)
+			return 0, r.err
+		case step.EOF:
+			r.steps = nil
+			return 0, io.EOF
+		}
+
+		if err := r.react(step); err != nil {
+			return 0, err
+		}
 	}
+}
 
-	sd := r.rd.SendData[r.i]
+func (r *dummyReader) send(b []byte, sd *SendData) (int, error) {
+	if r.frag == 0 {
+		r.frag = r.imp.fragment(len(sd.Send) - r.off)
+		delay := r.imp.delay(r.frag)
 
-	if sd.Timeout < 1 {
-		n := copy(b, sd.Send)
-		if n != len(sd.Send) {
-			return n, io.ErrShortBuffer
+		if r.off == 0 {
+			delay += time.Duration(sd.Timeout)
 		}
-		r.i++
 
-		return n, io.EOF
+		r.readyAt = time.Now().Add(delay)
 	}
 
-	select {
-	case <-r.done:
-		r.i = len(r.rd.SendData)
-		return 0, fmt.Errorf("interrupted")
-	case <-time.After(time.Duration(sd.Timeout)):
-		n := copy(b, sd.Send)
-		if n != len(sd.Send) {
-			return n, io.ErrShortBuffer
+	// The rest of the fragment that did not fit into b is sent immediately.
+	if wait := time.Until(r.readyAt); wait > 0 {
+		if wait > r.timeout {
+			wait = r.timeout
+		}
+
+		select {
+		case <-r.done:
+			r.steps = nil
+			return 0, fmt.Errorf("interrupted")
+		case <-time.After(wait):
 		}
 
-		r.i++
-		return n, io.EOF
-	case <-time.After(r.timeout):
-		return 0, os.ErrDeadlineExceeded
+		if time.Now().Before(r.readyAt) {
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+
+	n := copy(b, sd.Send[r.off:r.off+r.frag])
+	r.off += n
+	r.frag -= n
+
+	if r.off < len(sd.Send) {
+		return n, nil
 	}
+
+	r.steps = r.steps[1:]
+	r.off = 0
+	r.frag = 0
+
+	return n, io.EOF
 }
 
 func (r *dummyReader) SetTimeout(timeout time.Duration) {
@@ -80,12 +254,69 @@ func (r *dummyReader) SetTimeout(timeout time.Duration) {
 }
 
 func (r *dummyReader) Close() error {
-	r.i = len(r.rd.SendData)
 	close(r.done)
 
 	return nil
 }
 
+func newDummyReader(rd *ReaderData, timeout time.Duration) *dummyReader {
+	return &dummyReader{
+		steps:   rd.Steps,
+		rd:      *rd,
+		imp:     newImpairer(rd.Impairments),
+		done:    make(chan struct{}),
+		notify:  make(chan struct{}, 1),
+		timeout: timeout,
+	}
+}
+
+// LoadScenario reads the dummy transport scenario from the file.
+func LoadScenario(fileName string) (*ReaderData, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+
+	var rd ReaderData
+	if err2 := xml.Unmarshal(b, &rd); err2 != nil {
+		return nil, fmt.Errorf("cannot unmarhall data: %w", err2)
+	}
+
+	return &rd, nil
+}
+
+/*
+ScenarioPlayer plays the dummy transport scenario on the device side, e.g. in a fake device server.
+Read returns what the device sends, Write takes what the client sends.
+Read returns os.ErrDeadlineExceeded while the next chunk is not due yet or the scenario waits for input.
+*/
+type ScenarioPlayer struct {
+	r *dummyReader
+}
+
+func (p *ScenarioPlayer) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+func (p *ScenarioPlayer) Write(b []byte) (int, error) {
+	p.r.write(b)
+	return len(b), nil
+}
+
+// Finished reports whether all steps are played. It must be called from the goroutine that reads.
+func (p *ScenarioPlayer) Finished() bool {
+	return len(p.r.steps) == 0
+}
+
+func (p *ScenarioPlayer) Close() error {
+	return p.r.Close()
+}
+
+// NewScenarioPlayer returns the player of rd, Read waits no longer than timeout.
+func NewScenarioPlayer(rd *ReaderData, timeout time.Duration) *ScenarioPlayer {
+	return &ScenarioPlayer{r: newDummyReader(rd, timeout)}
+}
+
 type dummyTransport struct {
 	fileName string
 	timeout  time.Duration
@@ -97,22 +328,12 @@ func (t *dummyTransport) Open(ctx context.Context, host *host.Host) error {
 		return fmt.Errorf("dummyTransport timeout must be set")
 	}
 
-	b, err := os.ReadFile(t.fileName)
+	rd, err := LoadScenario(t.fileName)
 	if err != nil {
-		return fmt.Errorf("cannot read file: %w", err)
-	}
-
-	dr := &dummyReader{
-		done:    make(chan struct{}),
-		timeout: t.timeout,
-	}
-
-	if err2 := xml.Unmarshal(b, &dr.rd); err2 != nil {
-		close(dr.done)
-		return fmt.Errorf("cannot unmarhall data: %w", err2)
+		return err
 	}
 
-	t.dr = dr
+	t.dr = newDummyReader(rd, t.timeout)
 	return nil
 }
 
@@ -121,9 +342,18 @@ func (t *dummyTransport) Read(b []byte) (int, error) {
 }
 
 func (t *dummyTransport) Write(b []byte) (int, error) {
+	if t.dr != nil {
+		t.dr.write(b)
+	}
+
 	return len(b), nil
 }
 
+// KeepAlive does nothing, the scenario has no idle timers.
+func (t *dummyTransport) KeepAlive() error {
+	return nil
+}
+
 func (t *dummyTransport) Close() error {
 	if t.dr != nil {
 		return t.dr.Close()
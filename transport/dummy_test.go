@@ -2,6 +2,7 @@ package transport
 
 import (
 	"context"
+	"errors"
 	"io"
 	"os"
 	"path"
@@ -15,6 +16,8 @@ const (
 	testDataDir            = "testdata"
 	testDummyFileName      = "dummy_config.xml"
 	testDummyFileNotExists = "notextsts"
+	testDummyInteractive   = "dummy_interactive.xml"
+	testDummyImpaired      = "dummy_impaired.xml"
 )
 
 type DummyTransportTestSuite struct {
@@ -64,6 +67,152 @@ func (suite *DummyTransportTestSuite) TestTimeout() {
 	suite.ErrorIs(err, os.ErrDeadlineExceeded)
 }
 
+func (suite *DummyTransportTestSuite) openInteractive() {
+	suite.t.fileName = path.Join(testDataDir, testDummyInteractive)
+	suite.t.timeout = 100 * time.Millisecond
+	suite.Require().NoError(suite.t.Open(context.Background(), nil))
+}
+
+func (suite *DummyTransportTestSuite) read() string {
+	b := make([]byte, 1024)
+	n, err := suite.t.Read(b)
+	suite.Require().ErrorIs(err, io.EOF)
+
+	return string(b[:n])
+}
+
+func (suite *DummyTransportTestSuite) write(s string) {
+	_, err := suite.t.Write([]byte(s))
+	suite.Require().NoError(err)
+}
+
+func (suite *DummyTransportTestSuite) login() {
+	suite.Equal("Username: ", suite.read())
+	suite.write("admin\r")
+	suite.Equal("Password: ", suite.read())
+	suite.write("secret\r\n")
+	suite.Equal("sw1#", suite.read())
+}
+
+func (suite *DummyTransportTestSuite) TestExpectChoice() {
+	for _, tc := range []struct {
+		cmd    string
+		output []string
+	}{
+		{"show ver", []string{"Version 15.2\nsw1#", "sw1#"}},
+		{"exit", []string{"sw1#"}},
+		{"conf t", []string{"% Invalid input\nsw1#", "sw1#"}},
+	} {
+		suite.Run(tc.cmd, func() {
+			suite.openInteractive()
+			suite.login()
+			suite.write(tc.cmd + "\r")
+
+			for _, out := range tc.output {
+				suite.Equal(out, suite.read())
+			}
+
+			_, err := suite.t.Read(make([]byte, 1024))
+			suite.ErrorIs(err, io.EOF)
+			suite.NoError(suite.t.Close())
+		})
+	}
+}
+
+func (suite *DummyTransportTestSuite) TestExpectWaitsForWrite() {
+	suite.openInteractive()
+	suite.Equal("Username: ", suite.read())
+
+	_, err := suite.t.Read(make([]byte, 1024))
+	suite.ErrorIs(err, os.ErrDeadlineExceeded)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		suite.t.Write([]byte("adm"))
+		suite.t.Write([]byte("in\r"))
+	}()
+
+	suite.Equal("Password: ", suite.read())
+}
+
+func (suite *DummyTransportTestSuite) TestUnexpectedInput() {
+	suite.openInteractive()
+	suite.Equal("Username: ", suite.read())
+	suite.write("root\r")
+
+	_, err := suite.t.Read(make([]byte, 1024))
+	suite.ErrorIs(err, ErrUnexpectedInput)
+	suite.ErrorContains(err, `got "root"`)
+
+	_, err = suite.t.Read(make([]byte, 1024))
+	suite.ErrorIs(err, ErrUnexpectedInput)
+}
+
+// readFragments reads the scenario until the injected error and returns the sizes of the reads.
+func (suite *DummyTransportTestSuite) readFragments() ([]int, string) {
+	suite.t.fileName = path.Join(testDataDir, testDummyImpaired)
+	suite.t.timeout = time.Second
+	suite.Require().NoError(suite.t.Open(context.Background(), nil))
+	defer suite.t.Close()
+
+	var (
+		sizes []int
+		data  []byte
+	)
+
+	b := make([]byte, 1024)
+
+	for {
+		n, err := suite.t.Read(b)
+		if n > 0 {
+			sizes = append(sizes, n)
+			data = append(data, b[:n]...)
+		}
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			suite.ErrorIs(err, ErrInjected)
+			suite.ErrorContains(err, "connection reset by peer")
+
+			return sizes, string(data)
+		}
+	}
+}
+
+func (suite *DummyTransportTestSuite) TestImpairments() {
+	start := time.Now()
+	sizes, data := suite.readFragments()
+
+	suite.Equal("Username: sw1#sh ver\nVersion 15.2\nsw1#", data)
+	suite.GreaterOrEqual(time.Since(start), time.Duration(len(data))*time.Millisecond)
+
+	for _, n := range sizes {
+		suite.LessOrEqual(n, 3)
+	}
+
+	// The same seed splits the data the same way.
+	again, _ := suite.readFragments()
+	suite.Equal(sizes, again)
+
+}
+
+func (suite *DummyTransportTestSuite) TestEOF() {
+	f, err := os.CreateTemp(suite.T().TempDir(), "*.xml")
+	suite.Require().NoError(err)
+	_, err = f.WriteString(`<scenario><send>sw1#</send><eof/><send>never sent</send></scenario>`)
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+
+	suite.t.fileName = f.Name()
+	suite.t.timeout = time.Second
+	suite.Require().NoError(suite.t.Open(context.Background(), nil))
+
+	suite.Equal("sw1#", suite.read())
+
+	n, err := suite.t.Read(make([]byte, 1024))
+	suite.Zero(n)
+	suite.ErrorIs(err, io.EOF)
+}
+
 func TestDummyTransportTestSuite(t *testing.T) {
 	suite.Run(t, new(DummyTransportTestSuite))
 }
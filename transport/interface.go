@@ -2,12 +2,16 @@ package transport
 
 import (
 	"context"
+	"errors"
 	"io"
 	"time"
 
 	"github.com/jgivc/console/host"
 )
 
+// ErrAuthFailed is returned when the device rejects the account.
+var ErrAuthFailed = errors.New("authentication failed")
+
 type timeoutReader interface {
 	io.ReadCloser
 	SetTimeout(t time.Duration)
@@ -18,3 +22,9 @@ type Transport interface {
 	SetReadTimeout(t time.Duration)
 	io.ReadWriteCloser
 }
+
+// KeepAliver is implemented by transports that can keep the session alive
+// without sending anything to the device command line.
+type KeepAliver interface {
+	KeepAlive() error
+}
@@ -87,7 +87,10 @@ func (tr *timeoutReaderImpl) Read(p []byte) (int, error) {
 			tr.restData = data[n:]
 		}
 		return n, nil
-	case err := <-tr.chErr:
+	case err, ok := <-tr.chErr:
+		if !ok {
+			return 0, io.EOF
+		}
 		return 0, fmt.Errorf("read error: %w", err)
 	case <-time.After(tr.timeout):
 		return 0, os.ErrDeadlineExceeded
@@ -75,6 +75,8 @@ func (suite *TimeoutReaderSuite) TestRead_Error() {
 }
 
 func (suite *TimeoutReaderSuite) TestSetTimeout() {
+	suite.reader.On("Read", mock.Anything).Return(0, io.EOF).Maybe()
+
 	timeout := 2 * time.Second
 	bufferSize := 1024
 	tr := newTimeoutReader(context.Background(), suite.reader, timeout, bufferSize)
@@ -86,6 +88,8 @@ func (suite *TimeoutReaderSuite) TestSetTimeout() {
 }
 
 func (suite *TimeoutReaderSuite) TestClose() {
+	suite.reader.On("Read", mock.Anything).Return(0, io.EOF).Maybe()
+
 	timeout := 2 * time.Second
 	bufferSize := 1024
 	tr := newTimeoutReader(context.Background(), suite.reader, timeout, bufferSize)
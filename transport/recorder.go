@@ -0,0 +1,259 @@
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jgivc/console/host"
+)
+
+const (
+	RecordFormatCast  = "cast"  // asciinema v2
+	RecordFormatJSONL = "jsonl" // One JSON object per chunk
+
+	recordFilePerm   = 0600
+	recordDirPerm    = 0700
+	recordTimeFormat = "20060102T150405.000000"
+	recordMask       = "********"
+	castVersion      = 2
+
+	DirectionReceived = "received"
+	DirectionSent     = "sent"
+)
+
+var ErrNotSupported = errors.New("not supported")
+
+// recordSeq makes the record file names unique within the process.
+var recordSeq atomic.Int64
+
+// SecretWriter is implemented by transports that must not reveal some of the written data, e.g. passwords.
+type SecretWriter interface {
+	WriteSecret(b []byte) (int, error)
+}
+
+type castHeader struct {
+	Version   int    `json:"version"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp"`
+	Title     string `json:"title,omitempty"`
+}
+
+// RecordEntry is one line of the jsonl record. Data is replaced with DataBase64 if it is not valid UTF-8.
+type RecordEntry struct {
+	Time       time.Time `json:"time"`
+	Elapsed    float64   `json:"elapsed"`
+	Direction  string    `json:"direction"`
+	Data       string    `json:"data,omitempty"`
+	DataBase64 string    `json:"data_base64,omitempty"`
+}
+
+/*
+recorder wraps the Transport and writes both directions with timestamps to the file
+in asciinema v2 cast or jsonl format. Data written with WriteSecret is masked.
+A multibyte character split between reads or writes is recorded in one chunk.
+*/
+type recorder struct {
+	Transport
+	dir     string
+	format  string
+	mu      sync.Mutex
+	w       io.WriteCloser
+	enc     *json.Encoder
+	start   time.Time
+	pending map[string][]byte // Incomplete last rune of the direction
+}
+
+// fileName returns the unique name host_time_pid-seq.format, so sessions never share a file.
+func (r *recorder) fileName(h *host.Host) string {
+	return path.Join(r.dir, fmt.Sprintf("%s_%s_%d-%d.%s",
+		h.Host, r.start.Format(recordTimeFormat), os.Getpid(), recordSeq.Add(1), r.format))
+}
+
+func (r *recorder) Open(ctx context.Context, h *host.Host) error {
+	r.start = time.Now()
+	r.pending = make(map[string][]byte)
+
+	if err := os.MkdirAll(r.dir, recordDirPerm); err != nil {
+		return fmt.Errorf("cannot create record dir: %w", err)
+	}
+
+	f, err := os.OpenFile(r.fileName(h), os.O_CREATE|os.O_WRONLY|os.O_EXCL, recordFilePerm)
+	if err != nil {
+		return fmt.Errorf("cannot create record file: %w", err)
+	}
+
+	r.w = f
+	r.enc = json.NewEncoder(f)
+
+	if r.format == RecordFormatCast {
+		if err2 := r.enc.Encode(&castHeader{
+			Version:   castVersion,
+			Width:     sshTerminalWidth,
+			Height:    sshTerminalHeight,
+			Timestamp: r.start.Unix(),
+			Title:     h.GetHostPort(),
+		}); err2 != nil {
+			r.closeFile()
+			return fmt.Errorf("cannot write record header: %w", err2)
+		}
+	}
+
+	if err2 := r.Transport.Open(ctx, h); err2 != nil {
+		r.closeFile()
+		return err2
+	}
+
+	return nil
+}
+
+// incomplete returns the index of the incomplete rune at the end of b, or len(b).
+func incomplete(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+
+			break
+		}
+	}
+
+	return len(b)
+}
+
+func (r *recorder) record(direction string, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.w == nil {
+		return
+	}
+
+	b = append(r.pending[direction], b...)
+	cut := incomplete(b)
+	r.pending[direction] = append([]byte(nil), b[cut:]...)
+
+	r.write(direction, b[:cut])
+}
+
+// write writes the chunk to the record. Invalid UTF-8 is replaced in cast, it is base64 in jsonl.
+func (r *recorder) write(direction string, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(r.start).Seconds()
+
+	// The record is best effort, the session must not fail because of it.
+	if r.format == RecordFormatCast {
+		code := "o"
+		if direction == DirectionSent {
+			code = "i"
+		}
+
+		_ = r.enc.Encode([]interface{}{elapsed, code, string(b)})
+
+		return
+	}
+
+	entry := RecordEntry{
+		Time:      now,
+		Elapsed:   elapsed,
+		Direction: direction,
+	}
+
+	if utf8.Valid(b) {
+		entry.Data = string(b)
+	} else {
+		entry.DataBase64 = base64.StdEncoding.EncodeToString(b)
+	}
+
+	_ = r.enc.Encode(&entry)
+}
+
+func (r *recorder) Read(b []byte) (int, error) {
+	n, err := r.Transport.Read(b)
+	r.record(DirectionReceived, b[:n])
+
+	return n, err
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	n, err := r.Transport.Write(b)
+	r.record(DirectionSent, b[:n])
+
+	return n, err
+}
+
+func (r *recorder) WriteSecret(b []byte) (int, error) {
+	n, err := r.Transport.Write(b)
+	if n > 0 {
+		r.record(DirectionSent, []byte(recordMask))
+	}
+
+	return n, err
+}
+
+func (r *recorder) KeepAlive() error {
+	if ka, ok := r.Transport.(KeepAliver); ok {
+		return ka.KeepAlive()
+	}
+
+	return ErrNotSupported
+}
+
+func (r *recorder) closeFile() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.w == nil {
+		return nil
+	}
+
+	for _, direction := range []string{DirectionReceived, DirectionSent} {
+		r.write(direction, r.pending[direction])
+	}
+
+	err := r.w.Close()
+	r.w = nil
+
+	return err
+}
+
+func (r *recorder) Close() error {
+	return errors.Join(r.Transport.Close(), r.closeFile())
+}
+
+// NewRecorder returns Transport that records the session of t to the file in dir.
+// The file is named host_time_pid-seq.format and created on Open, dir is created if needed.
+func NewRecorder(t Transport, dir string, format string) (Transport, error) {
+	switch format {
+	case "":
+		format = RecordFormatCast
+	case RecordFormatCast, RecordFormatJSONL:
+	default:
+		return nil, fmt.Errorf("unknown record format: %s", format)
+	}
+
+	return &recorder{
+		Transport: t,
+		dir:       dir,
+		format:    format,
+	}, nil
+}
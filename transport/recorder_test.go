@@ -0,0 +1,162 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgivc/console/host"
+	"github.com/stretchr/testify/suite"
+)
+
+type RecorderTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *RecorderTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+func (suite *RecorderTestSuite) record(format string) []string {
+	dt := &dummyTransport{
+		fileName: path.Join(testDataDir, testDummyFileName),
+		timeout:  time.Second,
+	}
+
+	tr, err := NewRecorder(dt, suite.dir, format)
+	suite.Require().NoError(err)
+	suite.Require().NoError(tr.Open(context.Background(), &host.Host{Host: "sw1", Port: 23}))
+
+	b := make([]byte, 1024)
+	_, err = tr.Read(b)
+	suite.Require().ErrorIs(err, io.EOF)
+	_, err = tr.Write([]byte("admin"))
+	suite.Require().NoError(err)
+	_, err = tr.(SecretWriter).WriteSecret([]byte("p@ssw0rD"))
+	suite.Require().NoError(err)
+	suite.Require().NoError(tr.Close())
+
+	files, err := filepath.Glob(path.Join(suite.dir, "sw1_*."+format))
+	suite.Require().NoError(err)
+	suite.Require().Len(files, 1)
+
+	f, err := os.Open(files[0])
+	suite.Require().NoError(err)
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines
+}
+
+func (suite *RecorderTestSuite) TestJSONL() {
+	lines := suite.record(RecordFormatJSONL)
+	suite.Require().Len(lines, 3)
+
+	var entries []RecordEntry
+	for _, line := range lines {
+		var entry RecordEntry
+		suite.Require().NoError(json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	suite.Equal(DirectionReceived, entries[0].Direction)
+	suite.Contains(entries[0].Data, "Username: ")
+	suite.Equal(DirectionSent, entries[1].Direction)
+	suite.Equal("admin", entries[1].Data)
+	suite.Equal(recordMask, entries[2].Data)
+	suite.NotContains(lines[2], "p@ssw0rD")
+}
+
+func (suite *RecorderTestSuite) TestCast() {
+	lines := suite.record(RecordFormatCast)
+	suite.Require().Len(lines, 4)
+
+	var header castHeader
+	suite.Require().NoError(json.Unmarshal([]byte(lines[0]), &header))
+	suite.Equal(castVersion, header.Version)
+
+	var event []interface{}
+	suite.Require().NoError(json.Unmarshal([]byte(lines[2]), &event))
+	suite.Equal("i", event[1])
+	suite.Equal("admin", event[2])
+	suite.NotContains(lines[3], "p@ssw0rD")
+}
+
+// nopTransport accepts all writes.
+type nopTransport struct {
+	Transport
+}
+
+func (t *nopTransport) Open(ctx context.Context, h *host.Host) error { return nil }
+func (t *nopTransport) Write(b []byte) (int, error)                  { return len(b), nil }
+func (t *nopTransport) Close() error                                 { return nil }
+
+func (suite *RecorderTestSuite) TestUniqueFiles() {
+	dir := path.Join(suite.dir, "records")
+
+	for i := 0; i < 2; i++ {
+		tr, err := NewRecorder(&nopTransport{}, dir, RecordFormatJSONL)
+		suite.Require().NoError(err)
+		suite.Require().NoError(tr.Open(context.Background(), &host.Host{Host: "sw1", Port: 23}))
+		defer tr.Close()
+	}
+
+	files, err := filepath.Glob(path.Join(dir, "sw1_*.jsonl"))
+	suite.Require().NoError(err)
+	suite.Len(files, 2)
+}
+
+func (suite *RecorderTestSuite) TestSplitRune() {
+	tr, err := NewRecorder(&nopTransport{}, suite.dir, RecordFormatCast)
+	suite.Require().NoError(err)
+	suite.Require().NoError(tr.Open(context.Background(), &host.Host{Host: "sw1", Port: 23}))
+
+	data := []byte("интерфейс")
+	for _, chunk := range [][]byte{data[:3], data[3:7], data[7:]} {
+		_, err = tr.Write(chunk)
+		suite.Require().NoError(err)
+	}
+
+	_, err = tr.Write([]byte{0xd0})
+	suite.Require().NoError(err)
+	suite.Require().NoError(tr.Close())
+
+	files, err := filepath.Glob(path.Join(suite.dir, "sw1_*.cast"))
+	suite.Require().NoError(err)
+	suite.Require().Len(files, 1)
+
+	content, err := os.ReadFile(files[0])
+	suite.Require().NoError(err)
+
+	var text string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n")[1:] {
+		var event []interface{}
+		suite.Require().NoError(json.Unmarshal([]byte(line), &event))
+		text += event[2].(string)
+	}
+
+	// The incomplete rune left at the end is written on close.
+	suite.Equal("интерфейс\uFFFD", text)
+}
+
+func (suite *RecorderTestSuite) TestUnknownFormat() {
+	_, err := NewRecorder(&dummyTransport{}, suite.dir, "txt")
+	suite.Error(err)
+}
+
+func TestRecorderTestSuite(t *testing.T) {
+	suite.Run(t, new(RecorderTestSuite))
+}
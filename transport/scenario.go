@@ -0,0 +1,295 @@
+package transport
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnexpectedInput = errors.New("unexpected input")
+	ErrInjected        = errors.New("injected error")
+)
+
+/*
+Step is one step of the dummy transport scenario. Exactly one of the fields is set.
+
+	<send timeout="10ms">data</send>              send data to the console
+	<expect>admin</expect>                        the next line written by the console must be "admin"
+	<expect regex="true">^sh(ow)? ver</expect>    the next line must match the regular expression
+	<choice>                                      play the steps of the first branch matching the next line
+	    <when expect="sh ver">...</when>
+	    <when expect="^sh(ow)? run" regex="true">...</when>
+	    <when>...</when>                          default branch
+	</choice>
+	<error>connection reset</error>               fail every following read with ErrInjected
+	<eof/>                                        end the session, the rest of the steps is skipped
+*/
+type Step struct {
+	Send   *SendData
+	Expect *Expect
+	Choice *Choice
+	Error  *string
+	EOF    bool
+}
+
+type Steps []Step
+
+// Expect matches the line written by the console. A line is terminated with \r or \n,
+// leading and trailing spaces are ignored.
+type Expect struct {
+	Regex bool   `xml:"regex,attr"`
+	Value string `xml:",chardata"`
+	re    *regexp.Regexp
+}
+
+func (e *Expect) compile() error {
+	e.Value = strings.TrimSpace(e.Value)
+
+	if e.Regex {
+		re, err := regexp.Compile(e.Value)
+		if err != nil {
+			return fmt.Errorf("cannot compile expect: %w", err)
+		}
+
+		e.re = re
+	}
+
+	return nil
+}
+
+func (e *Expect) Match(line string) bool {
+	line = strings.TrimSpace(line)
+	if e.re != nil {
+		return e.re.MatchString(line)
+	}
+
+	return line == e.Value
+}
+
+func (e *Expect) String() string {
+	if e.Regex {
+		return fmt.Sprintf("/%s/", e.Value)
+	}
+
+	return fmt.Sprintf("%q", e.Value)
+}
+
+// When is the branch of the Choice. The branch without expect matches any line.
+type When struct {
+	Expect *Expect
+	Steps  Steps
+}
+
+func (w *When) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var e Expect
+
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "expect":
+			e.Value = attr.Value
+			w.Expect = &e
+		case "regex":
+			e.Regex = attr.Value == "true"
+		}
+	}
+
+	if w.Expect != nil {
+		if err := w.Expect.compile(); err != nil {
+			return err
+		}
+	}
+
+	return w.Steps.UnmarshalXML(d, start)
+}
+
+func (w *When) Match(line string) bool {
+	return w.Expect == nil || w.Expect.Match(line)
+}
+
+type Choice struct {
+	When []When `xml:"when"`
+}
+
+func (c *Choice) String() string {
+	var alts []string
+	for i := range c.When {
+		if c.When[i].Expect == nil {
+			alts = append(alts, "any")
+		} else {
+			alts = append(alts, c.When[i].Expect.String())
+		}
+	}
+
+	return strings.Join(alts, " or ")
+}
+
+func (s *Steps) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			step, err2 := decodeStep(d, t)
+			if err2 != nil {
+				return err2
+			}
+
+			*s = append(*s, step)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func decodeStep(d *xml.Decoder, start xml.StartElement) (Step, error) {
+	var step Step
+
+	switch start.Name.Local {
+	case "send":
+		step.Send = new(SendData)
+		if err := d.DecodeElement(step.Send, &start); err != nil {
+			return step, err
+		}
+
+		return step, step.Send.decode()
+	case "expect":
+		step.Expect = new(Expect)
+		if err := d.DecodeElement(step.Expect, &start); err != nil {
+			return step, err
+		}
+
+		return step, step.Expect.compile()
+	case "choice":
+		step.Choice = new(Choice)
+		return step, d.DecodeElement(step.Choice, &start)
+	case "error":
+		step.Error = new(string)
+		if err := d.DecodeElement(step.Error, &start); err != nil {
+			return step, err
+		}
+
+		*step.Error = strings.TrimSpace(*step.Error)
+
+		return step, nil
+	case "eof":
+		step.EOF = true
+		return step, d.Skip()
+	}
+
+	return step, fmt.Errorf("unknown scenario element: %s", start.Name.Local)
+}
+
+/*
+Impairments simulate a hostile link, they are set with the scenario attributes:
+
+	<scenario fragment="4" fragment_random="true" byte_latency="1ms" jitter="5ms" seed="42">
+
+Every send is split into fragments of fragment bytes (random 1..fragment bytes with fragment_random),
+each fragment is delayed by byte_latency per byte plus random 0..jitter.
+The random values are taken from seed, so the same scenario always plays the same way.
+*/
+type Impairments struct {
+	Fragment       int         `xml:"fragment,attr,omitempty"`
+	FragmentRandom bool        `xml:"fragment_random,attr,omitempty"`
+	ByteLatency    ReadTimeout `xml:"byte_latency,attr"`
+	Jitter         ReadTimeout `xml:"jitter,attr"`
+	Seed           int64       `xml:"seed,attr,omitempty"`
+}
+
+func (im *Impairments) unmarshalXMLAttr(attr xml.Attr) error {
+	var err error
+
+	switch attr.Name.Local {
+	case "fragment":
+		im.Fragment, err = strconv.Atoi(attr.Value)
+	case "fragment_random":
+		im.FragmentRandom, err = strconv.ParseBool(attr.Value)
+	case "byte_latency":
+		err = im.ByteLatency.UnmarshalXMLAttr(attr)
+	case "jitter":
+		err = im.Jitter.UnmarshalXMLAttr(attr)
+	case "seed":
+		im.Seed, err = strconv.ParseInt(attr.Value, 10, 64)
+	}
+
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %w", attr.Name.Local, err)
+	}
+
+	return nil
+}
+
+// impairer applies Impairments to one session.
+type impairer struct {
+	Impairments
+	rnd *rand.Rand
+}
+
+func newImpairer(im Impairments) *impairer {
+	return &impairer{
+		Impairments: im,
+		rnd:         rand.New(rand.NewSource(im.Seed)), //nolint:gosec
+	}
+}
+
+// fragment returns the size of the next fragment of left bytes.
+func (i *impairer) fragment(left int) int {
+	size := i.Fragment
+	if size < 1 {
+		return left
+	}
+
+	if i.FragmentRandom {
+		size = 1 + i.rnd.Intn(size)
+	}
+
+	if size > left {
+		return left
+	}
+
+	return size
+}
+
+// delay returns the delay of the fragment of size bytes.
+func (i *impairer) delay(size int) time.Duration {
+	d := time.Duration(i.ByteLatency) * time.Duration(size)
+	if i.Jitter > 0 {
+		d += time.Duration(i.rnd.Int63n(int64(i.Jitter)))
+	}
+
+	return d
+}
+
+func (rd *ReaderData) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	rd.XMLName = start.Name
+	rd.Steps = nil
+	rd.SendData = nil
+	rd.Impairments = Impairments{}
+
+	for _, attr := range start.Attr {
+		if err := rd.Impairments.unmarshalXMLAttr(attr); err != nil {
+			return err
+		}
+	}
+
+	if err := rd.Steps.UnmarshalXML(d, start); err != nil {
+		return err
+	}
+
+	for _, step := range rd.Steps {
+		if step.Send != nil {
+			rd.SendData = append(rd.SendData, *step.Send)
+		}
+	}
+
+	return nil
+}
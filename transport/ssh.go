@@ -2,8 +2,10 @@ package transport
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/jgivc/console/host"
@@ -37,6 +39,13 @@ func (t *sshTransport) Open(ctx context.Context, host *host.Host) error {
 
 	sshConn, chans, reqs, err := ssh.NewClientConn(conn, host.GetHostPort(), config)
 	if err != nil {
+		conn.Close()
+
+		// x/crypto/ssh has no typed error for rejected credentials.
+		if strings.Contains(err.Error(), sshAuthFailed) {
+			return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+		}
+
 		return err
 	}
 
@@ -103,6 +112,13 @@ func (t *sshTransport) Close() error {
 	return t.client.Close()
 }
 
+// KeepAlive sends keepalive@openssh.com global request. Any reply, even a failure one,
+// means that the session is still alive.
+func (t *sshTransport) KeepAlive() error {
+	_, _, err := t.client.SendRequest(sshKeepAliveRequest, true, nil)
+	return err
+}
+
 func (t *sshTransport) SetReadTimeout(d time.Duration) {
 	t.r.SetTimeout(d)
 }
@@ -47,6 +47,11 @@ func (t *telnetTransport) Close() error {
 	return err
 }
 
+// KeepAlive sends IAC NOP. Unlike AYT it produces no output on the device side.
+func (t *telnetTransport) KeepAlive() error {
+	return t.conn.SendCommand(telnet.NOP)
+}
+
 func (t *telnetTransport) SetReadTimeout(d time.Duration) {
 	t.r.SetTimeout(d)
 }
@@ -82,6 +82,15 @@ func DialContext(ctx context.Context, addr string) (*Conn, error) {
 	return &clientConn, nil
 }
 
+// NewConn wraps the accepted connection, e.g. on the server side of a test device.
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{
+		conn:       conn,
+		dataReader: newDataReader(conn),
+		dataWriter: newDataWriter(conn),
+	}
+}
+
 // DialTLS makes a (secure) TELNETS client connection to the system's 'loopback address'
 // (also known as "localhost" or 127.0.0.1).
 func DialTLS(tlsConfig *tls.Config) (*Conn, error) {
@@ -155,6 +164,15 @@ func (clientConn *Conn) Write(p []byte) (n int, err error) {
 	return clientConn.dataWriter.Write(p)
 }
 
+// SendCommand sends the TELNET (and TELNETS) command code 'cmd' (for example NOP or AYT)
+// prefixed with IAC to the server.
+//
+// Unlike Write, the command code is not escaped.
+func (clientConn *Conn) SendCommand(cmd byte) error {
+	_, err := clientConn.conn.Write([]byte{IAC, cmd})
+	return err
+}
+
 // LocalAddr returns the local network address.
 func (clientConn *Conn) LocalAddr() net.Addr {
 	return clientConn.conn.LocalAddr()
@@ -14,6 +14,8 @@ const (
 	WONT = 252
 	DO   = 253
 	DONT = 254
+	NOP  = 241
+	AYT  = 246
 )
 
 var (
@@ -122,7 +124,7 @@ func (r *internalDataReader) Read(data []byte) (n int, err error) {
 					return n, err
 				}
 
-			case SE:
+			case SE, NOP:
 				_, err = r.buffered.Discard(1)
 				if nil != err {
 					return n, err
@@ -70,6 +70,11 @@ func TestDataReader(t *testing.T) {
 			Expected: []byte{255, 255, 255, 255, 255},
 		},
 
+		{
+			Bytes:    []byte("apple\xff\xf1banana"),
+			Expected: []byte("applebanana"),
+		},
+
 		{
 			Bytes:    []byte("apple\xff\xffbanana\xff\xffcherry"),
 			Expected: []byte("apple\xffbanana\xffcherry"),
@@ -33,8 +33,24 @@ type Factory struct {
 	DummyFileName string
 	ReadTimeout   time.Duration
 	BufSize       int
+	RecordDir     string // If set, sessions are recorded to this dir
+	RecordFormat  string
+	CaptureDir    string // If set, sessions are saved to this dir as dummy transport scenarios
 }
 
 func (f *Factory) GetTransport(host *host.Host) (Transport, error) {
-	return New(host.TransportType, f.ReadTimeout, f.BufSize, f.DummyFileName)
+	t, err := New(host.TransportType, f.ReadTimeout, f.BufSize, f.DummyFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.CaptureDir != "" {
+		t = NewCapturer(t, f.CaptureDir)
+	}
+
+	if f.RecordDir != "" {
+		return NewRecorder(t, f.RecordDir, f.RecordFormat)
+	}
+
+	return t, nil
 }
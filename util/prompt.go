@@ -2,13 +2,17 @@ package util
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"regexp"
 	"time"
 )
 
-var ErrNoPromptFound = errors.New("no prompt found")
+var (
+	ErrNoPromptFound  = errors.New("no prompt found")
+	ErrConnectionLost = errors.New("connection lost")
+)
 
 type TimeoutReader interface {
 	io.ReadCloser
@@ -48,6 +52,7 @@ func (r *promptReader) Reset() {
 /*
 The read method reads from the underlying TimeoutReader until it finds a prompt.
 If the prompt is found, io.EOF will be returned. If deadline is reached ErrNoPromptFound will be retirned.
+If the underlying reader fails or is closed, the returned error wraps ErrConnectionLost.
 */
 func (r *promptReader) Read(p []byte) (int, error) {
 	if r.err != nil {
@@ -75,7 +80,7 @@ func (r *promptReader) Read(p []byte) (int, error) {
 		n, err := r.buf.ReadFrom(r.reader)
 		if err != nil {
 			if errors.Is(err, io.EOF) && n < 1 {
-				r.err = ErrNoPromptFound
+				r.err = fmt.Errorf("%w: %w", ErrNoPromptFound, ErrConnectionLost)
 				return 0, r.err
 			}
 
@@ -86,6 +91,11 @@ func (r *promptReader) Read(p []byte) (int, error) {
 				}
 				continue
 			}
+
+			if !errors.Is(err, io.EOF) {
+				r.err = fmt.Errorf("%w: %w", ErrConnectionLost, err)
+				return 0, r.err
+			}
 		}
 
 		if loc := r.reg.FindIndex(r.buf.Bytes()); loc != nil {